@@ -29,13 +29,21 @@ func (c CFDrainCLI) Run(conn plugin.CliConnection, args []string) {
 		log.Fatalf("Expected at least 1 argument, but got 0.")
 	}
 
-	ccCurler := cloudcontroller.NewCLICurlClient(conn)
-	sdClient := drain.NewServiceDrainLister(ccCurler)
 	logger := newLogger(os.Stdout)
 	httpClient := &http.Client{
 		Timeout: 30 * time.Second,
 	}
-	downloader := command.NewGithubReleaseDownloader(httpClient, logger)
+	var downloader command.Downloader = command.NewGithubReleaseDownloader(httpClient, logger)
+
+	args, dryRun := stripDryRun(args)
+	if dryRun {
+		logger.Printf("Dry run: no changes will be made.")
+		conn = command.NewDryRunConnection(conn, logger)
+		downloader = command.NewDryRunDownloader(logger)
+	}
+
+	ccCurler := cloudcontroller.NewCLICurlClient(conn)
+	sdClient := drain.NewServiceDrainLister(ccCurler)
 
 	switch args[0] {
 	case "drain":
@@ -49,23 +57,69 @@ func (c CFDrainCLI) Run(conn plugin.CliConnection, args []string) {
 		}
 		command.DeleteDrain(conn, args[1:], logger, os.Stdin, sdClient)
 	case "bind-drain":
-		if len(args) < 3 {
+		if len(args) < 2 {
 			c.exitWithUsage("bind-drain")
 		}
-		command.BindDrain(conn, sdClient, args[1:], logger)
+		appLister := cloudcontroller.NewAppListerClient(ccCurler)
+		command.BindDrain(conn, sdClient, appLister, args[1:], logger)
+	case "update-drain":
+		if len(args) < 2 {
+			c.exitWithUsage("update-drain")
+		}
+		command.UpdateDrain(conn, sdClient, args[1:], logger)
+	case "unbind-drain":
+		if len(args) < 3 {
+			c.exitWithUsage("unbind-drain")
+		}
+		command.UnbindDrain(conn, sdClient, args[1:], logger)
 	case "drains":
-		command.Drains(conn, nil, logger, os.Stdout, sdClient)
+		if len(args) > 1 && args[1] == "apply" {
+			appLister := cloudcontroller.NewAppListerClient(ccCurler)
+			envReader := cloudcontroller.NewClient(ccCurler)
+			tokenFetcher := command.NewTokenFetcher(configPath(log))
+			command.ApplyDrains(conn, sdClient, appLister, envReader, downloader, tokenFetcher, args[2:], logger)
+			return
+		}
+		if len(args) > 1 && args[1] == "export" {
+			envReader := cloudcontroller.NewClient(ccCurler)
+			command.ExportDrains(conn, sdClient, envReader, args[2:], logger, os.Stdout)
+			return
+		}
+		command.Drains(conn, args[1:], logger, os.Stdout, sdClient)
+	case "drain-check":
+		if len(args) < 2 {
+			c.exitWithUsage("drain-check")
+		}
+		command.DrainCheck(conn, args[1:], logger, os.Stdout, sdClient, drain.NewChecker())
 	case "drain-space":
 		if len(args) < 2 {
 			c.exitWithUsage("drain-space", "SYSLOG_DRAIN_URL required of the form syslog://destinaton.url:port")
 		}
 		tokenFetcher := command.NewTokenFetcher(configPath(log))
 		command.PushSpaceDrain(conn, args[1:], downloader, tokenFetcher, logger)
+	case "drain-org":
+		if len(args) < 2 {
+			c.exitWithUsage("drain-org", "SYSLOG_DRAIN_URL required of the form syslog://destinaton.url:port")
+		}
+		tokenFetcher := command.NewTokenFetcher(configPath(log))
+		command.PushOrgDrain(conn, args[1:], downloader, tokenFetcher, logger)
 	case "delete-drain-space":
 		if len(args) < 2 {
 			c.exitWithUsage("delete-drain-space")
 		}
 		command.DeleteSpaceDrain(conn, args[1:], logger, os.Stdin, sdClient, command.DeleteDrain)
+	case "delete-drain-org":
+		if len(args) < 2 {
+			c.exitWithUsage("delete-drain-org")
+		}
+		remover := struct {
+			*cloudcontroller.UnbindDrainClient
+			*cloudcontroller.DeleteDrainClient
+		}{
+			cloudcontroller.NewUnbindDrainClient(ccCurler),
+			cloudcontroller.NewDeleteDrainClient(ccCurler),
+		}
+		command.DeleteOrgDrain(conn, args[1:], logger, os.Stdin, sdClient, remover)
 	}
 }
 
@@ -86,48 +140,125 @@ func (c CFDrainCLI) GetMetadata() plugin.PluginMetadata {
 		Commands: []plugin.Command{
 			{
 				Name:     "drains",
-				HelpText: "Lists all services for syslog drains.",
+				HelpText: "Lists all services for syslog drains, or applies or exports a drain manifest for the space.",
 				UsageDetails: plugin.Usage{
-					Usage: "drains",
+					Usage: "drains [--org ORG | --all-spaces] [--orphaned] [--output FORMAT]\n   drains apply -f MANIFEST [--prune] [--skip-validation] [--dry-run]\n   drains export [-f MANIFEST]",
+					Options: map[string]string{
+						"f":                "Drain manifest to apply, or to export to instead of stdout.",
+						"-prune":           "Delete drains in the space that are not in the manifest.",
+						"-skip-validation": "Apply the manifest without validating the syslog drain URLs.",
+						"-dry-run":         "Print the cf commands that would be run without changing anything.",
+						"-orphaned":        "Only list drains that are not bound to any app.",
+						"-org":             "List the drains of every space in the given org.",
+						"-all-spaces":      "List the drains of every space in every org.",
+						"-output":          "Output format of the drains (table, json, yaml, csv). Default is table.",
+					},
 				},
 			},
 			{
 				Name:     "drain",
 				HelpText: "Creates a user provided service for syslog drains and binds it to a given application.",
 				UsageDetails: plugin.Usage{
-					Usage: "drain APP_NAME SYSLOG_DRAIN_URL [--drain-name NAME] [--type TYPE]",
+					Usage: "drain APP_NAME SYSLOG_DRAIN_URL [--drain-name NAME] [--type TYPE] [--skip-validation] [--dry-run]",
 					Options: map[string]string{
-						"-drain-name": "The name of the drain that will be created. If excluded, the drain name will be `cf-drain-UUID`.",
-						"-type":       "The type of logs to be sent to the syslog drain. Available types: `logs`, `metrics`, and `all`. Default is `logs`",
+						"-drain-name":      "The name of the drain that will be created. If excluded, the drain name will be `cf-drain-UUID`.",
+						"-type":            "The type of logs to be sent to the syslog drain. Available types: `logs`, `metrics`, and `all`. Default is `logs`",
+						"-skip-validation": "Create the drain without validating the syslog drain URL.",
+						"-dry-run":         "Print the cf commands that would be run without changing anything.",
 					},
 				},
 			},
 			{
 				Name:     "bind-drain",
-				HelpText: "Binds an application to an existing syslog drain.",
+				HelpText: "Binds applications to an existing syslog drain.",
+				UsageDetails: plugin.Usage{
+					Usage: "bind-drain [APP_NAME...] DRAIN_NAME [--app-pattern PATTERN] [--label KEY=VALUE] [--dry-run]",
+					Options: map[string]string{
+						"-app-pattern": "Bind every app whose name matches the glob, or the regex when wrapped in slashes (e.g. /^orders-/). Can be repeated.",
+						"-label":       "Bind every app matching the label selector (e.g. team=orders). Can be repeated; apps must match all selectors.",
+						"-dry-run":     "Print the cf commands that would be run without changing anything.",
+					},
+				},
+			},
+			{
+				Name:     "unbind-drain",
+				HelpText: "Unbinds an application from a syslog drain without deleting the drain.",
 				UsageDetails: plugin.Usage{
-					Usage: "bind-drain APP_NAME DRAIN_NAME",
+					Usage: "unbind-drain APP_NAME DRAIN_NAME [--dry-run]",
+					Options: map[string]string{
+						"-dry-run": "Print the cf commands that would be run without changing anything.",
+					},
+				},
+			},
+			{
+				Name:     "update-drain",
+				HelpText: "Updates the syslog drain URL of an existing drain without unbinding its applications.",
+				UsageDetails: plugin.Usage{
+					Usage: "update-drain DRAIN_NAME [--url SYSLOG_DRAIN_URL] [--type TYPE] [--restage] [--skip-validation] [--dry-run]",
+					Options: map[string]string{
+						"-url":             "The new syslog drain URL.",
+						"-type":            "The type of logs to be sent to the syslog drain. Available types: `logs`, `metrics`, and `all`.",
+						"-restage":         "Restage the applications bound to the drain after updating it. Default is false.",
+						"-skip-validation": "Update the drain without validating the syslog drain URL.",
+						"-dry-run":         "Print the cf commands that would be run without changing anything.",
+					},
 				},
 			},
 			{
 				Name:     "delete-drain",
 				HelpText: "Unbinds the service from applications and deletes the service.",
 				UsageDetails: plugin.Usage{
-					Usage: "delete-drain DRAIN_NAME [--force]",
+					Usage: "delete-drain DRAIN_NAME [--force] [--dry-run]",
 					Options: map[string]string{
-						"-force": "Skip warning prompt. Default is false.",
+						"-force":   "Skip warning prompt. Default is false.",
+						"-dry-run": "Print the cf commands that would be run without changing anything.",
 					},
 				},
 			},
+			{
+				Name:     "drain-check",
+				HelpText: "Connects to a syslog drain and sends it a test message.",
+				UsageDetails: plugin.Usage{
+					Usage: "drain-check DRAIN_NAME|SYSLOG_DRAIN_URL",
+				},
+			},
 			{
 				Name:     "drain-space",
 				HelpText: "Pushes app to bind all apps in the space to the configured syslog drain.",
 				UsageDetails: plugin.Usage{
-					Usage: "drain-space SYSLOG_DRAIN_URL [--drain-name NAME] [--path PATH] [--type TYPE]",
+					Usage: "drain-space SYSLOG_DRAIN_URL [--drain-name NAME] [--path PATH] [--type TYPE] [--include SELECTOR] [--exclude SELECTOR] [--exclude-apps REGEX] [--client-id CLIENT --client-secret-env VAR] [--credential-store STORE] [--skip-validation] [--dry-run]",
+					Options: map[string]string{
+						"-drain-name":        "Name for the space drain.",
+						"-path":              "Path to the space drain app to push. If omitted the latest release will be downloaded.",
+						"-type":              "Which log type to filter on (logs, metrics, all). Default is all.",
+						"-include":           "Only bind apps matching the label selector (e.g. team=orders).",
+						"-exclude":           "Do not bind apps matching the label selector (e.g. load-generator=true).",
+						"-exclude-apps":      "Do not bind apps whose name matches the regex.",
+						"-client-id":         "UAA client the drain authenticates as instead of with your refresh token.",
+						"-client-secret-env": "Environment variable holding the secret of the UAA client.",
+						"-credential-store":  "Where the drain app keeps your refresh token (user-provided, credhub, env). Default is user-provided.",
+						"-skip-validation":   "Create the drain without validating the syslog drain URL.",
+						"-dry-run":           "Print the cf commands that would be run without changing anything.",
+					},
+				},
+			},
+			{
+				Name:     "drain-org",
+				HelpText: "Pushes app to bind all apps in every space of the org to the configured syslog drain.",
+				UsageDetails: plugin.Usage{
+					Usage: "drain-org SYSLOG_DRAIN_URL [--drain-name NAME] [--path PATH] [--type TYPE] [--include SELECTOR] [--exclude SELECTOR] [--exclude-apps REGEX] [--client-id CLIENT --client-secret-env VAR] [--credential-store STORE] [--skip-validation] [--dry-run]",
 					Options: map[string]string{
-						"-drain-name": "Name for the space drain.",
-						"-path":       "Path to the space drain app to push. If omitted the latest release will be downloaded.",
-						"-type":       "Which log type to filter on (logs, metrics, all). Default is all.",
+						"-drain-name":        "Name for the org drain and the drain created in each space.",
+						"-path":              "Path to the space drain app to push. If omitted the latest release will be downloaded.",
+						"-type":              "Which log type to filter on (logs, metrics, all). Default is all.",
+						"-include":           "Only bind apps matching the label selector (e.g. team=orders).",
+						"-exclude":           "Do not bind apps matching the label selector (e.g. load-generator=true).",
+						"-exclude-apps":      "Do not bind apps whose name matches the regex.",
+						"-client-id":         "UAA client the drain authenticates as instead of with your refresh token.",
+						"-client-secret-env": "Environment variable holding the secret of the UAA client.",
+						"-credential-store":  "Where the drain app keeps your refresh token (user-provided, credhub, env). Default is user-provided.",
+						"-skip-validation":   "Create the drains without validating the syslog drain URL.",
+						"-dry-run":           "Print the cf commands that would be run without changing anything.",
 					},
 				},
 			},
@@ -135,9 +266,21 @@ func (c CFDrainCLI) GetMetadata() plugin.PluginMetadata {
 				Name:     "delete-drain-space",
 				HelpText: "Deletes space drain app and unbinds all the apps in the space from the configured syslog drain.",
 				UsageDetails: plugin.Usage{
-					Usage: "delete-drain-space DRAIN_NAME [--force]",
+					Usage: "delete-drain-space DRAIN_NAME [--force] [--dry-run]",
 					Options: map[string]string{
-						"-force": "Skip warning prompt. Default is false.",
+						"-force":   "Skip warning prompt. Default is false.",
+						"-dry-run": "Print the cf commands that would be run without changing anything.",
+					},
+				},
+			},
+			{
+				Name:     "delete-drain-org",
+				HelpText: "Deletes org drain app and the drains it created in every space of the org.",
+				UsageDetails: plugin.Usage{
+					Usage: "delete-drain-org DRAIN_NAME [--force] [--dry-run]",
+					Options: map[string]string{
+						"-force":   "Skip warning prompt. Default is false.",
+						"-dry-run": "Print the cf commands that would be run without changing anything.",
 					},
 				},
 			},
@@ -212,6 +355,27 @@ func (l *logger) Print(a ...interface{}) {
 	fmt.Fprint(os.Stdout, a...)
 }
 
+// stripDryRun removes the global --dry-run flag from the arguments. It is
+// handled here so every command supports it without parsing it.
+func stripDryRun(args []string) ([]string, bool) {
+	var (
+		stripped []string
+		dryRun   bool
+	)
+	for i, a := range args {
+		if a == "--" {
+			stripped = append(stripped, args[i:]...)
+			break
+		}
+		if a == "--dry-run" {
+			dryRun = true
+			continue
+		}
+		stripped = append(stripped, a)
+	}
+	return stripped, dryRun
+}
+
 func configPath(log *log.Logger) string {
 	if cfHome := os.Getenv("CF_HOME"); cfHome != "" {
 		return path.Join(cfHome, ".cf", "config.json")
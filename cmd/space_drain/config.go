@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"time"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
 )
@@ -11,6 +12,19 @@ import (
 type Config struct {
 	SpaceID string `env:"SPACE_ID, required"`
 
+	// InstanceIndex is set by CF. Only the instance with index 0
+	// reconciles, the other instances are standbys.
+	InstanceIndex int `env:"CF_INSTANCE_INDEX"`
+
+	// ShutdownTimeout is how long open HTTP requests are waited for on
+	// shutdown.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT"`
+
+	// DrainScope is either space or org. With an org scope the apps in
+	// every space of ORG_ID are bound.
+	DrainScope string `env:"DRAIN_SCOPE"`
+	OrgID      string `env:"ORG_ID"`
+
 	DrainName string `env:"DRAIN_NAME, required"`
 	DrainURL  string `env:"DRAIN_URL, required"`
 	DrainType string `env:"DRAIN_TYPE"`
@@ -19,10 +33,71 @@ type Config struct {
 	UAAAddr  string `env:"UAA_ADDR, required"`
 	ClientID string `env:"CLIENT_ID, required"`
 
-	SkipCertVerify bool `env:"SKIP_CERT_VERIFY"`
+	SkipCertVerify         bool `env:"SKIP_CERT_VERIFY"`
+	SkipDrainURLValidation bool `env:"SKIP_DRAIN_URL_VALIDATION"`
+	PruneBindings          bool `env:"PRUNE_BINDINGS"`
+
+	IncludeLabelSelector string `env:"INCLUDE_LABEL_SELECTOR"`
+	ExcludeLabelSelector string `env:"EXCLUDE_LABEL_SELECTOR"`
+	ExcludeAppNameRegex  string `env:"EXCLUDE_APP_NAME_REGEX"`
+
+	ReconcileInterval   time.Duration `env:"RECONCILE_INTERVAL"`
+	ReconcileJitter     time.Duration `env:"RECONCILE_JITTER"`
+	MaxReconcileBackoff time.Duration `env:"MAX_RECONCILE_BACKOFF"`
+
+	PollAuditEvents        bool          `env:"POLL_AUDIT_EVENTS"`
+	AuditEventPollInterval time.Duration `env:"AUDIT_EVENT_POLL_INTERVAL"`
+
+	// Concurrency is the number of apps bound at once.
+	Concurrency int `env:"CONCURRENCY"`
+
+	// CCRequestsPerSecond limits the rate of Cloud Controller requests. It
+	// is unlimited when zero.
+	CCRequestsPerSecond float64 `env:"CC_REQUESTS_PER_SECOND"`
+	CCRequestBurst      int     `env:"CC_REQUEST_BURST"`
+
+	// DrainScopeCacheTTL is how long an app is remembered to be a drain
+	// app or not.
+	DrainScopeCacheTTL time.Duration `env:"DRAIN_SCOPE_CACHE_TTL"`
+
+	// HealthFailureThreshold is how long reconciles may fail, or the leader
+	// may go without a reconcile, before /health reports the app as
+	// unhealthy.
+	HealthFailureThreshold time.Duration `env:"HEALTH_FAILURE_THRESHOLD"`
+
+	// MetricsEmitter is where counters and gauges are emitted to besides
+	// /metrics: loggregator, stdout or empty for nowhere.
+	MetricsEmitter      string        `env:"METRICS_EMITTER"`
+	MetricsEmitInterval time.Duration `env:"METRICS_EMIT_INTERVAL"`
+	LoggregatorAddr     string        `env:"LOGGREGATOR_ADDR"`
+	LoggregatorCAPath   string        `env:"LOGGREGATOR_CA_PATH"`
+	LoggregatorCertPath string        `env:"LOGGREGATOR_CERT_PATH"`
+	LoggregatorKeyPath  string        `env:"LOGGREGATOR_KEY_PATH"`
+
+	// A failed token fetch is retried TOKEN_FETCH_ATTEMPTS times. The
+	// process exits after TOKEN_MAX_CONSECUTIVE_FAILURES failed fetches in
+	// a row.
+	TokenFetchAttempts          int           `env:"TOKEN_FETCH_ATTEMPTS"`
+	TokenFetchBackoff           time.Duration `env:"TOKEN_FETCH_BACKOFF"`
+	TokenFetchMaxBackoff        time.Duration `env:"TOKEN_FETCH_MAX_BACKOFF"`
+	TokenMaxConsecutiveFailures int           `env:"TOKEN_MAX_CONSECUTIVE_FAILURES"`
+
+	// CredentialStore is where the refresh token or client secret is
+	// stored: env, user-provided or credhub. The service stores read it
+	// from the service instance CREDENTIAL_SERVICE_NAME bound to the app.
+	CredentialStore       string `env:"CREDENTIAL_STORE"`
+	CredentialServiceName string `env:"CREDENTIAL_SERVICE_NAME"`
 
 	VCAPApplication Application
-	RefreshToken    string `env:"REFRESH_TOKEN"`
+}
+
+// leader reports whether this instance reconciles.
+func (c Config) leader() bool {
+	return c.InstanceIndex == 0
+}
+
+func (c Config) orgScoped() bool {
+	return c.DrainScope == "org"
 }
 
 type Application struct {
@@ -31,12 +106,98 @@ type Application struct {
 
 func loadConfig() Config {
 	cfg := Config{
-		DrainType: "all",
+		DrainScope:                  "space",
+		CredentialStore:             "env",
+		ShutdownTimeout:             5 * time.Second,
+		DrainType:                   "all",
+		ReconcileInterval:           time.Minute,
+		ReconcileJitter:             10 * time.Second,
+		MaxReconcileBackoff:         10 * time.Minute,
+		AuditEventPollInterval:      5 * time.Second,
+		HealthFailureThreshold:      15 * time.Minute,
+		Concurrency:                 4,
+		TokenFetchAttempts:          3,
+		TokenFetchBackoff:           time.Second,
+		TokenFetchMaxBackoff:        30 * time.Second,
+		TokenMaxConsecutiveFailures: 30,
+		CCRequestsPerSecond:         20,
+		CCRequestBurst:              20,
+		DrainScopeCacheTTL:          10 * time.Minute,
+		MetricsEmitInterval:         time.Minute,
+		LoggregatorAddr:             "localhost:3458",
 	}
 	if err := envstruct.Load(&cfg); err != nil {
 		log.Fatal(err)
 	}
 
+	switch cfg.DrainScope {
+	case "space":
+	case "org":
+		if cfg.OrgID == "" {
+			log.Fatal("ORG_ID is required when DRAIN_SCOPE is org")
+		}
+	default:
+		log.Fatalf("DRAIN_SCOPE must be space or org, got %s", cfg.DrainScope)
+	}
+
+	switch cfg.CredentialStore {
+	case "env":
+	case "user-provided", "credhub":
+		if cfg.CredentialServiceName == "" {
+			log.Fatalf("CREDENTIAL_SERVICE_NAME is required when CREDENTIAL_STORE is %s", cfg.CredentialStore)
+		}
+	default:
+		log.Fatalf("CREDENTIAL_STORE must be env, user-provided or credhub, got %s", cfg.CredentialStore)
+	}
+
+	if cfg.ReconcileInterval <= 0 {
+		log.Fatalf("RECONCILE_INTERVAL must be positive, got %s", cfg.ReconcileInterval)
+	}
+
+	if cfg.MaxReconcileBackoff < cfg.ReconcileInterval {
+		cfg.MaxReconcileBackoff = cfg.ReconcileInterval
+	}
+
+	if cfg.PollAuditEvents && cfg.AuditEventPollInterval <= 0 {
+		log.Fatalf("AUDIT_EVENT_POLL_INTERVAL must be positive, got %s", cfg.AuditEventPollInterval)
+	}
+
+	if cfg.Concurrency < 1 {
+		log.Fatalf("CONCURRENCY must be at least 1, got %d", cfg.Concurrency)
+	}
+
+	if cfg.CCRequestsPerSecond < 0 {
+		log.Fatalf("CC_REQUESTS_PER_SECOND must not be negative, got %g", cfg.CCRequestsPerSecond)
+	}
+
+	if cfg.TokenFetchAttempts < 1 {
+		log.Fatalf("TOKEN_FETCH_ATTEMPTS must be at least 1, got %d", cfg.TokenFetchAttempts)
+	}
+
+	if cfg.HealthFailureThreshold < 0 {
+		log.Fatalf("HEALTH_FAILURE_THRESHOLD must not be negative, got %s", cfg.HealthFailureThreshold)
+	}
+
+	// The leader is unhealthy when it has not reconciled within the
+	// threshold, which must leave room for the interval between reconciles.
+	if cfg.HealthFailureThreshold <= cfg.ReconcileInterval+cfg.ReconcileJitter {
+		log.Fatalf("HEALTH_FAILURE_THRESHOLD must be longer than RECONCILE_INTERVAL plus RECONCILE_JITTER, got %s", cfg.HealthFailureThreshold)
+	}
+
+	switch cfg.MetricsEmitter {
+	case "", "stdout":
+	case "loggregator":
+		if cfg.LoggregatorCAPath == "" || cfg.LoggregatorCertPath == "" || cfg.LoggregatorKeyPath == "" {
+			log.Fatal("LOGGREGATOR_CA_PATH, LOGGREGATOR_CERT_PATH and LOGGREGATOR_KEY_PATH are required when METRICS_EMITTER is loggregator")
+		}
+	default:
+		log.Fatalf("METRICS_EMITTER must be loggregator or stdout, got %s", cfg.MetricsEmitter)
+	}
+
+	if cfg.MetricsEmitter != "" && cfg.MetricsEmitInterval <= 0 {
+		log.Fatalf("METRICS_EMIT_INTERVAL must be positive, got %s", cfg.MetricsEmitInterval)
+	}
+
 	//TODO: The application ID needs to come from CAPI
 	va := os.Getenv("VCAP_APPLICATION")
 	var app Application
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
+	"sync"
+	"syscall"
 	"time"
 
 	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
@@ -20,6 +26,7 @@ func main() {
 	log.Printf("starting space drain...")
 	defer log.Printf("space drain closing...")
 
+	rand.Seed(time.Now().UnixNano())
 	cfg := loadConfig()
 
 	httpClient := &http.Client{
@@ -36,136 +43,184 @@ func main() {
 		log.Fatalf("Failed to create UAA Client: %s", err)
 	}
 
+	var curler *cloudcontroller.HTTPCurlClient
+	store := newCredentialStore(cfg, curlFunc(func(url, method, body string) ([]byte, error) {
+		return curler.Curl(url, method, body)
+	}), log)
+
 	var restager *cloudcontroller.Restager
-	saveAndRestager := cloudcontroller.SaveAndRestagerFunc(func(rt string) {
-		restager.SaveAndRestage(rt)
+	saveAndRestager := cloudcontroller.SaveAndRestagerFunc(func(rt string) error {
+		return restager.SaveAndRestage(rt)
 	})
 
-	tokenManager := cloudcontroller.NewTokenManager(
-		uaaClient,
-		cfg.ClientID,
-		cfg.RefreshToken,
-		cfg.VCAPApplication.ID,
-		cfg.SkipCertVerify,
-		log,
-	)
+	st := newStatus(cfg.HealthFailureThreshold, cfg.leader())
 
-	curler := cloudcontroller.NewHTTPCurlClient(cfg.APIAddr, httpClient, tokenManager, saveAndRestager)
-	restager = cloudcontroller.NewRestager(
-		cfg.VCAPApplication.ID,
-		curler,
-		log,
-	)
-
-	drainLister := drain.NewServiceDrainLister(curler)
-	drainCreator := cloudcontroller.NewCreateDrainClient(curler)
-	drainBinder := cloudcontroller.NewBindDrainClient(curler)
-	appLister := cloudcontroller.NewAppListerClient(curler)
+	retryPolicy := cloudcontroller.TokenRetryPolicy{
+		Attempts:               cfg.TokenFetchAttempts,
+		Backoff:                cfg.TokenFetchBackoff,
+		MaxBackoff:             cfg.TokenFetchMaxBackoff,
+		MaxConsecutiveFailures: cfg.TokenMaxConsecutiveFailures,
+	}
 
-	createAndBind(drainLister, drainCreator, drainBinder, appLister, curler, cfg, log)
-	go func() {
-		for range time.Tick(time.Minute) {
-			createAndBind(drainLister, drainCreator, drainBinder, appLister, curler, cfg, log)
+	// A client secret selects the client_credentials grant for CLIENT_ID
+	// instead of a user's refresh token.
+	var (
+		tokenFetcher cloudcontroller.TokenFetcher
+		refreshToken string
+	)
+	if clientSecret := store.ClientSecret(); clientSecret != "" {
+		tokenFetcher = cloudcontroller.NewClientCredentialsTokenFetcher(
+			uaaClient,
+			cfg.ClientID,
+			clientSecret,
+			cfg.SkipCertVerify,
+			log,
+			cloudcontroller.WithClientCredentialsTokenRetryPolicy(retryPolicy),
+		)
+	} else {
+		refreshToken, err = store.RefreshToken()
+		if err != nil {
+			log.Fatalf("Failed to read the refresh token: %s", err)
 		}
-	}()
-
-	http.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(fmt.Sprintf(`{"version": "%s"}`, version)))
-	})
-	http.ListenAndServe(":"+os.Getenv("PORT"), nil)
-}
 
-func createAndBind(
-	drainLister *drain.ServiceDrainLister,
-	drainCreator *cloudcontroller.CreateDrainClient,
-	drainBinder *cloudcontroller.BindDrainClient,
-	appLister *cloudcontroller.AppListerClient,
-	curler cloudcontroller.Curler,
-	cfg Config,
-	log *log.Logger,
-) {
-	drains, err := drainLister.Drains(cfg.SpaceID)
-	if err != nil {
-		log.Printf("failed to fetch drains: %s", err)
-		return
+		tokenFetcher = cloudcontroller.NewTokenManager(
+			uaaClient,
+			cfg.ClientID,
+			refreshToken,
+			cfg.VCAPApplication.ID,
+			cfg.SkipCertVerify,
+			log,
+			cloudcontroller.WithTokenRetryPolicy(retryPolicy),
+		)
 	}
 
-	drain, ok := hasDrain(cfg.DrainName, drains)
-	if !ok {
-		log.Printf("creating %s drain...", cfg.DrainName)
-		if err := drainCreator.CreateDrain(
-			cfg.DrainName,
-			cfg.DrainURL,
-			cfg.SpaceID,
-			cfg.DrainType,
-		); err != nil {
-			log.Printf("failed to create drain: %s", err)
-			return
+	m := newDrainMetrics()
+	curler = cloudcontroller.NewHTTPCurlClient(
+		cfg.APIAddr,
+		cloudcontroller.NewInstrumentedDoer(httpClient, m.ccRequests, m.ccLatency),
+		statusTokenFetcher{f: tokenFetcher, s: st},
+		saveAndRestager,
+		cloudcontroller.WithHTTPCurlClientRefreshToken(refreshToken),
+	)
+	restager = cloudcontroller.NewRestager(cfg.VCAPApplication.ID, store, curler)
+
+	var excludeAppName *regexp.Regexp
+	if cfg.ExcludeAppNameRegex != "" {
+		excludeAppName, err = regexp.Compile(cfg.ExcludeAppNameRegex)
+		if err != nil {
+			log.Fatalf("Invalid EXCLUDE_APP_NAME_REGEX: %s", err)
 		}
-		log.Printf("created %s drain", cfg.DrainName)
+	}
 
-		// go again so that ListDrains can find it and get its guid.
-		createAndBind(drainLister, drainCreator, drainBinder, appLister, curler, cfg, log)
-		return
+	var ccCurler cloudcontroller.Curler = curler
+	if cfg.CCRequestsPerSecond > 0 {
+		ccCurler = cloudcontroller.NewRateLimitedCurler(curler, cfg.CCRequestsPerSecond, cfg.CCRequestBurst)
 	}
 
-	apps, err := appLister.ListApps(cfg.SpaceID)
-	if err != nil {
-		log.Printf("failed to list apps: %s", err)
-		return
+	r := &reconciler{
+		drainLister: drain.NewServiceDrainLister(ccCurler),
+		drainCreator: cloudcontroller.NewCreateDrainClient(
+			ccCurler,
+			cloudcontroller.WithCreateDrainSkipValidation(cfg.SkipDrainURLValidation),
+			cloudcontroller.WithCreateDrainTags(drain.SpaceDrainTag(cfg.VCAPApplication.ID)),
+		),
+		drainBinder:   cloudcontroller.NewBindDrainClient(ccCurler),
+		drainUnbinder: cloudcontroller.NewUnbindDrainClient(ccCurler),
+		drainDeleter:  cloudcontroller.NewDeleteDrainClient(ccCurler),
+		appLister:     cloudcontroller.NewAppListerClient(ccCurler),
+		spaceLister:   cloudcontroller.NewSpaceListerClient(ccCurler),
+		eventClient:   cloudcontroller.NewAuditEventClient(ccCurler),
+		curler:        ccCurler,
+		scopes:        newScopeCache(cfg.DrainScopeCacheTTL),
+		status:        st,
+		metrics:       m,
+		cfg:           cfg,
+		log:           log,
+
+		excludeAppName: excludeAppName,
 	}
 
-	log.Printf("binding %d apps to drain...", len(apps))
-	for _, app := range apps {
-		if isSpaceDrain(curler, app.Guid, log) {
-			continue
-		}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.emit(ctx, cfg, log)
+	}()
 
-		if containsApp(app.Guid, drain.AppGuids) || app.Guid == cfg.VCAPApplication.ID {
-			continue
+	if cfg.leader() {
+		if cfg.PollAuditEvents {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.pollEvents(ctx, time.Now())
+			}()
 		}
 
-		if err := drainBinder.BindDrain(app.Guid, drain.Guid); err != nil {
-			log.Printf("failed to bind %s to drain: %s", app.Guid, err)
-			continue
-		}
-		drain.AppGuids = append(drain.AppGuids, app.Guid)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.run(ctx)
+		}()
+	} else {
+		log.Printf("instance %d is a standby, only instance 0 reconciles", cfg.InstanceIndex)
 	}
-	log.Printf("done binding apps to drain.")
-}
 
-func containsApp(appGuid string, guids []string) bool {
-	for _, g := range guids {
-		if g == appGuid {
-			return true
-		}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fmt.Sprintf(`{"version": "%s"}`, version)))
+	})
+	mux.HandleFunc("/status", st.serveStatus)
+	mux.HandleFunc("/health", st.serveHealth)
+	mux.Handle("/metrics", m.registry)
+
+	server := &http.Server{
+		Addr:    ":" + os.Getenv("PORT"),
+		Handler: mux,
 	}
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve: %s", err)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	<-signals
 
-	return false
+	log.Printf("finishing in-flight reconcile...")
+	cancel()
+	wg.Wait()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down HTTP server: %s", err)
+	}
 }
 
-func isSpaceDrain(curler cloudcontroller.Curler, appGUID string, log *log.Logger) bool {
-	c := cloudcontroller.NewClient(curler)
+// newCredentialStore returns the store of the refresh token or client secret
+// configured with CREDENTIAL_STORE.
+func newCredentialStore(cfg Config, c cloudcontroller.AuthCurler, log *log.Logger) cloudcontroller.CredentialStore {
+	if cfg.CredentialStore == "env" {
+		return cloudcontroller.NewEnvCredentialStore(cfg.VCAPApplication.ID, c)
+	}
 
-	envs, err := c.EnvVars(appGUID)
+	service, err := cloudcontroller.FindBoundService(os.Getenv("VCAP_SERVICES"), cfg.CredentialServiceName)
 	if err != nil {
-		log.Printf("failed to read env variables for %s: %s", appGUID, err)
-		return true
+		log.Fatalf("Failed to find the credential service: %s", err)
 	}
 
-	if envs["DRAIN_SCOPE"] == "space" {
-		return true
+	if cfg.CredentialStore == "credhub" {
+		return cloudcontroller.NewCredHubServiceCredentialStore(service, c)
 	}
-
-	return false
+	return cloudcontroller.NewUserProvidedServiceCredentialStore(service, c)
 }
 
-func hasDrain(name string, drains []drain.Drain) (drain.Drain, bool) {
-	for _, drain := range drains {
-		if drain.Name == name {
-			return drain, true
-		}
-	}
+// curlFunc lets the credential store use the curler that is created after
+// it.
+type curlFunc func(url, method, body string) ([]byte, error)
 
-	return drain.Drain{}, false
+func (f curlFunc) Curl(url, method, body string) ([]byte, error) {
+	return f(url, method, body)
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/metrics"
+	loggregator "code.cloudfoundry.org/go-loggregator"
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+)
+
+// drainMetrics are the metrics of the space drain served on /metrics.
+type drainMetrics struct {
+	registry *metrics.Registry
+
+	reconciles        *metrics.Counter
+	reconcileDuration *metrics.Histogram
+	binds             *metrics.Counter
+	drainsCreated     *metrics.Counter
+	apps              *metrics.Gauge
+	ccRequests        *metrics.Counter
+	ccLatency         *metrics.Histogram
+}
+
+func newDrainMetrics() *drainMetrics {
+	r := metrics.NewRegistry()
+
+	return &drainMetrics{
+		registry: r,
+
+		reconciles: r.NewCounter(
+			"space_drain_reconciles_total",
+			"Reconciles of the drain by result.",
+			"result",
+		),
+		reconcileDuration: r.NewHistogram(
+			"space_drain_reconcile_duration_seconds",
+			"Duration of reconciles of the drain.",
+			[]float64{.1, .5, 1, 2.5, 5, 10, 30, 60, 120},
+		),
+		binds: r.NewCounter(
+			"space_drain_binds_total",
+			"Binds of apps to the drain by result.",
+			"result",
+		),
+		drainsCreated: r.NewCounter(
+			"space_drain_drains_created_total",
+			"Drains created.",
+		),
+		apps: r.NewGauge(
+			"space_drain_apps",
+			"Apps in scope of the drain by space and state. Unbound apps failed to bind.",
+			"space", "state",
+		),
+		ccRequests: r.NewCounter(
+			"space_drain_cloud_controller_requests_total",
+			"Requests to Cloud Controller by method and status code.",
+			"method", "code",
+		),
+		ccLatency: r.NewHistogram(
+			"space_drain_cloud_controller_request_duration_seconds",
+			"Latency of requests to Cloud Controller by method.",
+			nil,
+			"method",
+		),
+	}
+}
+
+// emit forwards the counters and gauges to the configured metrics emitter
+// on every METRICS_EMIT_INTERVAL until the context is done.
+func (m *drainMetrics) emit(ctx context.Context, cfg Config, log *log.Logger) {
+	var c pulseemitter.LogClient
+	switch cfg.MetricsEmitter {
+	case "stdout":
+		c = metrics.NewWriterLogClient(os.Stdout)
+	case "loggregator":
+		tlsConfig, err := loggregator.NewIngressTLSConfig(
+			cfg.LoggregatorCAPath,
+			cfg.LoggregatorCertPath,
+			cfg.LoggregatorKeyPath,
+		)
+		if err != nil {
+			log.Fatalf("Failed to load Loggregator TLS config: %s", err)
+		}
+
+		c, err = loggregator.NewIngressClient(
+			tlsConfig,
+			loggregator.WithAddr(cfg.LoggregatorAddr),
+			loggregator.WithLogger(log),
+		)
+		if err != nil {
+			log.Fatalf("Failed to create Loggregator client: %s", err)
+		}
+	default:
+		return
+	}
+
+	emitter := pulseemitter.New(
+		c,
+		pulseemitter.WithPulseInterval(cfg.MetricsEmitInterval),
+		pulseemitter.WithSourceID(cfg.VCAPApplication.ID),
+	)
+	metrics.NewForwarder(m.registry, emitter).Run(ctx, cfg.MetricsEmitInterval)
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
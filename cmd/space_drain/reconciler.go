@@ -0,0 +1,606 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"regexp"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+	"code.cloudfoundry.org/cf-drain-cli/internal/drainurl"
+)
+
+// maxBackoffShift caps the exponent of the reconcile backoff so the delay
+// does not overflow.
+const maxBackoffShift = 16
+
+var errDrainNotFound = errors.New("drain not found")
+
+// reconciler binds the apps in the space to the space drain. A full
+// reconcile lists every app in the space. When audit events are polled,
+// newly created apps are bound as soon as their create event shows up.
+//
+// With an org scope every space in the org is reconciled the same way,
+// each space getting its own drain.
+type reconciler struct {
+	mu sync.Mutex
+
+	drainLister   *drain.ServiceDrainLister
+	drainCreator  *cloudcontroller.CreateDrainClient
+	drainBinder   *cloudcontroller.BindDrainClient
+	drainUnbinder *cloudcontroller.UnbindDrainClient
+	drainDeleter  *cloudcontroller.DeleteDrainClient
+	appLister     *cloudcontroller.AppListerClient
+	spaceLister   *cloudcontroller.SpaceListerClient
+	eventClient   *cloudcontroller.AuditEventClient
+	curler        cloudcontroller.Curler
+	scopes        *scopeCache
+	status        *status
+	metrics       *drainMetrics
+	cfg           Config
+	log           *log.Logger
+
+	// excludeAppName excludes apps whose name matches. It is nil if no
+	// apps are excluded by name.
+	excludeAppName *regexp.Regexp
+}
+
+// appFilter limits the apps bound to the drain to the apps selected by the
+// include and exclude settings.
+type appFilter struct {
+	// include holds the GUIDs of the included apps. It is nil if every
+	// app is included.
+	include     map[string]bool
+	exclude     map[string]bool
+	excludeName *regexp.Regexp
+}
+
+func (f appFilter) matches(app cloudcontroller.App) bool {
+	if f.include != nil && !f.include[app.Guid] {
+		return false
+	}
+
+	if f.exclude[app.Guid] {
+		return false
+	}
+
+	return f.excludeName == nil || !f.excludeName.MatchString(app.Name)
+}
+
+// run reconciles until the context is done. A reconcile in flight is
+// finished first. After a failed reconcile the interval is doubled for
+// every consecutive failure, up to the max backoff.
+func (r *reconciler) run(ctx context.Context) {
+	var failures int
+	for {
+		start := time.Now()
+		err := r.reconcile()
+		r.metrics.reconcileDuration.Observe(time.Since(start).Seconds())
+		r.metrics.reconciles.Inc(result(err))
+		r.status.reconciled(err)
+		if err != nil {
+			failures++
+			r.log.Printf("reconcile failed (%d consecutive failures): %s", failures, err)
+		} else {
+			failures = 0
+		}
+
+		t := time.NewTimer(r.nextInterval(failures))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (r *reconciler) nextInterval(failures int) time.Duration {
+	interval := r.cfg.ReconcileInterval
+	if failures > 0 {
+		shift := failures
+		if shift > maxBackoffShift {
+			shift = maxBackoffShift
+		}
+
+		interval = interval << uint(shift)
+		if interval > r.cfg.MaxReconcileBackoff || interval <= 0 {
+			interval = r.cfg.MaxReconcileBackoff
+		}
+	}
+
+	if r.cfg.ReconcileJitter > 0 {
+		interval += time.Duration(rand.Int63n(int64(r.cfg.ReconcileJitter)))
+	}
+
+	return interval
+}
+
+// pollEvents binds apps as soon as their audit.app.create event is seen.
+// Events are requested from the time of the last seen event so no event is
+// missed between polls. Events already handled are skipped. It returns
+// when the context is done.
+func (r *reconciler) pollEvents(ctx context.Context, since time.Time) {
+	ticker := time.NewTicker(r.cfg.AuditEventPollInterval)
+	defer ticker.Stop()
+
+	seen := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		events, err := r.appCreateEvents(since)
+		if err != nil {
+			r.log.Printf("failed to fetch audit events: %s", err)
+			continue
+		}
+
+		apps := make(map[string][]cloudcontroller.App)
+		for _, e := range events {
+			if seen[e.Guid] {
+				continue
+			}
+
+			if e.CreatedAt.After(since) {
+				since = e.CreatedAt
+				seen = make(map[string]bool)
+			}
+			seen[e.Guid] = true
+
+			spaceID := e.SpaceGuid
+			if spaceID == "" {
+				spaceID = r.cfg.SpaceID
+			}
+			apps[spaceID] = append(apps[spaceID], cloudcontroller.App{
+				Name: e.TargetName,
+				Guid: e.TargetGuid,
+			})
+		}
+
+		for spaceID, spaceApps := range apps {
+			r.log.Printf("binding %d new apps to drain in space %s...", len(spaceApps), spaceID)
+			err = r.bindApps(spaceID, spaceApps)
+			if err == errDrainNotFound {
+				// The next full reconcile creates the drain and binds the
+				// apps.
+				continue
+			}
+			if err != nil {
+				r.log.Printf("failed to bind new apps: %s", err)
+			}
+		}
+	}
+}
+
+func (r *reconciler) appCreateEvents(since time.Time) ([]cloudcontroller.AuditEvent, error) {
+	if r.cfg.orgScoped() {
+		return r.eventClient.OrgAppCreateEvents(r.cfg.OrgID, since)
+	}
+
+	return r.eventClient.AppCreateEvents(r.cfg.SpaceID, since)
+}
+
+// reconcile creates the drain if it does not exist and binds every app in
+// the space to it. With an org scope every space in the org is reconciled,
+// a failing space does not stop the others.
+func (r *reconciler) reconcile() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status.reconcileStarted()
+	r.scopes.expire()
+	if !r.cfg.orgScoped() {
+		return r.createAndBind(r.cfg.SpaceID)
+	}
+
+	spaces, err := r.spaceLister.ListSpaces(r.cfg.OrgID)
+	if err != nil {
+		return fmt.Errorf("failed to list spaces: %s", err)
+	}
+
+	var failed int
+	spaceIDs := make([]string, 0, len(spaces))
+	for _, s := range spaces {
+		spaceIDs = append(spaceIDs, s.Guid)
+		if err := r.createAndBind(s.Guid); err != nil {
+			r.log.Printf("failed to reconcile space %s: %s", s.Name, err)
+			failed++
+		}
+	}
+	for _, id := range r.status.retainSpaces(spaceIDs) {
+		r.metrics.apps.Delete(id, "bound")
+		r.metrics.apps.Delete(id, "unbound")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to reconcile %d of %d spaces", failed, len(spaces))
+	}
+
+	return nil
+}
+
+func (r *reconciler) createAndBind(spaceID string) error {
+	drains, err := r.drainLister.Drains(spaceID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch drains: %s", err)
+	}
+
+	if r.cfg.PruneBindings {
+		r.removeStaleDrains(drains)
+	}
+
+	drain, ok := hasDrain(r.cfg.DrainName, drains)
+	if ok && r.cfg.PruneBindings {
+		drainURL, err := cloudcontroller.DrainURLWithType(r.cfg.DrainURL, r.cfg.DrainType)
+		if err != nil {
+			return err
+		}
+
+		if !drainurl.Equal(drain.DrainURL, drainURL) {
+			r.log.Printf("%s drain URL changed, recreating drain...", r.cfg.DrainName)
+			if err := r.removeDrain(drain); err != nil {
+				return err
+			}
+			ok = false
+		}
+	}
+
+	if !ok {
+		r.log.Printf("creating %s drain...", r.cfg.DrainName)
+		if err := r.drainCreator.CreateDrain(
+			r.cfg.DrainName,
+			r.cfg.DrainURL,
+			spaceID,
+			r.cfg.DrainType,
+		); err != nil {
+			return fmt.Errorf("failed to create drain: %s", err)
+		}
+		r.metrics.drainsCreated.Inc()
+		r.log.Printf("created %s drain", r.cfg.DrainName)
+
+		// Fetch the drains again to get the guid of the new drain. If it
+		// is not listed yet the next reconcile binds the apps, the drain
+		// is never created twice.
+		drains, err = r.drainLister.Drains(spaceID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch drains: %s", err)
+		}
+
+		drain, ok = hasDrain(r.cfg.DrainName, drains)
+		if !ok {
+			return fmt.Errorf("created %s drain not found", r.cfg.DrainName)
+		}
+	}
+
+	apps, err := r.appLister.ListApps(spaceID)
+	if err != nil {
+		return fmt.Errorf("failed to list apps: %s", err)
+	}
+
+	filter, err := r.appFilter(spaceID)
+	if err != nil {
+		return err
+	}
+
+	r.log.Printf("binding %d apps to drain...", len(apps))
+	var (
+		mu      sync.Mutex
+		keep    = make(map[string]bool)
+		newly   int
+		unbound int
+	)
+	r.parallel(apps, func(app cloudcontroller.App) {
+		if !r.cfg.PruneBindings && containsApp(app.Guid, drain.AppGuids) {
+			// Only look up the scope of bound apps when they might be
+			// unbound.
+			return
+		}
+
+		inScope, err := r.inScope(app, filter)
+		if err != nil {
+			// Neither bind nor unbind apps whose scope is unknown.
+			r.log.Printf("failed to read env variables for %s: %s", app.Guid, err)
+			mu.Lock()
+			keep[app.Guid] = true
+			mu.Unlock()
+			return
+		}
+
+		if !inScope {
+			return
+		}
+
+		alreadyBound := containsApp(app.Guid, drain.AppGuids)
+		ok := r.bindApp(drain, app)
+
+		mu.Lock()
+		defer mu.Unlock()
+		keep[app.Guid] = true
+		switch {
+		case !ok:
+			unbound++
+		case !alreadyBound:
+			newly++
+		}
+	})
+	r.log.Printf("done binding apps to drain.")
+
+	bound := len(drain.AppGuids) + newly
+	if r.cfg.PruneBindings {
+		bound -= r.unbindApps(drain, keep)
+	}
+	r.recordBound(spaceID, bound)
+	r.metrics.apps.Set(float64(unbound), spaceID, "unbound")
+
+	return nil
+}
+
+// bindApps binds the given apps to the drain. It returns errDrainNotFound
+// if the drain has not been created yet.
+func (r *reconciler) bindApps(spaceID string, apps []cloudcontroller.App) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	drains, err := r.drainLister.Drains(spaceID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch drains: %s", err)
+	}
+
+	drain, ok := hasDrain(r.cfg.DrainName, drains)
+	if !ok {
+		return errDrainNotFound
+	}
+
+	filter, err := r.appFilter(spaceID)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu     sync.Mutex
+		newly  int
+		failed int
+	)
+	r.parallel(apps, func(app cloudcontroller.App) {
+		inScope, err := r.inScope(app, filter)
+		if err != nil {
+			r.log.Printf("failed to read env variables for %s: %s", app.Guid, err)
+			return
+		}
+
+		if !inScope {
+			return
+		}
+
+		alreadyBound := containsApp(app.Guid, drain.AppGuids)
+		ok := r.bindApp(drain, app)
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case !ok:
+			failed++
+		case !alreadyBound:
+			newly++
+		}
+	})
+
+	// Apps that failed to bind are retried and counted by the next full
+	// reconcile.
+	r.recordBound(spaceID, len(drain.AppGuids)+newly)
+	r.metrics.apps.Add(float64(failed), spaceID, "unbound")
+
+	return nil
+}
+
+// recordBound records the number of apps bound to the drain in the space
+// for /status and /metrics.
+func (r *reconciler) recordBound(spaceID string, bound int) {
+	r.status.bound(spaceID, bound)
+	r.metrics.apps.Set(float64(bound), spaceID, "bound")
+}
+
+// appFilter looks up the apps matching the include and exclude label
+// selectors in the space.
+func (r *reconciler) appFilter(spaceID string) (appFilter, error) {
+	f := appFilter{
+		excludeName: r.excludeAppName,
+	}
+
+	if r.cfg.IncludeLabelSelector != "" {
+		apps, err := r.appLister.ListAppsByLabel(spaceID, r.cfg.IncludeLabelSelector)
+		if err != nil {
+			return appFilter{}, fmt.Errorf("failed to list included apps: %s", err)
+		}
+		f.include = appGuidSet(apps)
+	}
+
+	if r.cfg.ExcludeLabelSelector != "" {
+		apps, err := r.appLister.ListAppsByLabel(spaceID, r.cfg.ExcludeLabelSelector)
+		if err != nil {
+			return appFilter{}, fmt.Errorf("failed to list excluded apps: %s", err)
+		}
+		f.exclude = appGuidSet(apps)
+	}
+
+	return f, nil
+}
+
+func appGuidSet(apps []cloudcontroller.App) map[string]bool {
+	guids := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		guids[app.Guid] = true
+	}
+	return guids
+}
+
+// inScope reports whether the app should be bound to the drain. This drain
+// app, other space and org drain apps and apps not matching the filter are
+// never bound.
+func (r *reconciler) inScope(app cloudcontroller.App, filter appFilter) (bool, error) {
+	if app.Guid == r.cfg.VCAPApplication.ID || !filter.matches(app) {
+		return false, nil
+	}
+
+	drainApp, ok := r.scopes.get(app.Guid)
+	if !ok {
+		var err error
+		drainApp, err = isDrainApp(r.curler, app.Guid)
+		if err != nil {
+			return false, err
+		}
+		r.scopes.set(app.Guid, drainApp)
+	}
+
+	return !drainApp, nil
+}
+
+// parallel calls f for every app from at most CONCURRENCY goroutines. It
+// returns once every call returned.
+func (r *reconciler) parallel(apps []cloudcontroller.App, f func(cloudcontroller.App)) {
+	work := make(chan cloudcontroller.App)
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for app := range work {
+				f(app)
+			}
+		}()
+	}
+
+	for _, app := range apps {
+		work <- app
+	}
+	close(work)
+	wg.Wait()
+}
+
+// bindApp binds the app to the drain unless it is already bound. It reports
+// whether the app is bound.
+func (r *reconciler) bindApp(drain drain.Drain, app cloudcontroller.App) bool {
+	if containsApp(app.Guid, drain.AppGuids) {
+		return true
+	}
+
+	err := r.drainBinder.BindDrain(app.Guid, drain.Guid)
+	r.metrics.binds.Inc(result(err))
+	if err != nil {
+		r.log.Printf("failed to bind %s to drain: %s", app.Guid, err)
+		r.status.bindFailed(app.Guid, err)
+		return false
+	}
+	r.status.bindSucceeded(app.Guid)
+	r.log.Printf("bound %s to %s drain", app.Guid, drain.Name)
+
+	return true
+}
+
+// unbindApps unbinds every app from the drain that is not in keep. It
+// returns the number of unbound apps.
+func (r *reconciler) unbindApps(drain drain.Drain, keep map[string]bool) int {
+	var unbound int
+	for _, appGuid := range drain.AppGuids {
+		if keep[appGuid] {
+			continue
+		}
+
+		if err := r.drainUnbinder.UnbindDrain(appGuid, drain.Guid); err != nil {
+			r.log.Printf("failed to unbind %s from %s drain: %s", appGuid, drain.Name, err)
+			continue
+		}
+		r.log.Printf("unbound %s from %s drain", appGuid, drain.Name)
+		unbound++
+	}
+
+	return unbound
+}
+
+// removeStaleDrains removes drains created by this space drain under a
+// different name, e.g. before DRAIN_NAME was changed.
+func (r *reconciler) removeStaleDrains(drains []drain.Drain) {
+	tag := drain.SpaceDrainTag(r.cfg.VCAPApplication.ID)
+	for _, d := range drains {
+		if d.Name == r.cfg.DrainName || !hasTag(tag, d.Tags) {
+			continue
+		}
+
+		r.log.Printf("removing stale %s drain...", d.Name)
+		if err := r.removeDrain(d); err != nil {
+			r.log.Printf("failed to remove stale drain: %s", err)
+		}
+	}
+}
+
+// removeDrain unbinds every app from the drain and deletes it.
+func (r *reconciler) removeDrain(d drain.Drain) error {
+	for _, appGuid := range d.AppGuids {
+		if err := r.drainUnbinder.UnbindDrain(appGuid, d.Guid); err != nil {
+			return fmt.Errorf("failed to unbind %s from %s drain: %s", appGuid, d.Name, err)
+		}
+		r.log.Printf("unbound %s from %s drain", appGuid, d.Name)
+	}
+
+	if err := r.drainDeleter.DeleteDrain(d.Guid); err != nil {
+		return fmt.Errorf("failed to delete %s drain: %s", d.Name, err)
+	}
+	r.log.Printf("deleted %s drain", d.Name)
+
+	return nil
+}
+
+func hasTag(tag string, tags []string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsApp(appGuid string, guids []string) bool {
+	for _, g := range guids {
+		if g == appGuid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDrainApp reports whether the app is a space or org drain.
+func isDrainApp(curler cloudcontroller.Curler, appGUID string) (bool, error) {
+	c := cloudcontroller.NewClient(curler)
+
+	envs, err := c.EnvVars(appGUID)
+	if err != nil {
+		return false, err
+	}
+
+	switch envs["DRAIN_SCOPE"] {
+	case "space", "org":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func hasDrain(name string, drains []drain.Drain) (drain.Drain, bool) {
+	for _, drain := range drains {
+		if drain.Name == name {
+			return drain, true
+		}
+	}
+
+	return drain.Drain{}, false
+}
@@ -0,0 +1,990 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+)
+
+var _ = Describe("reconciler", func() {
+	var (
+		curler *stubCurler
+		cfg    Config
+	)
+
+	BeforeEach(func() {
+		curler = newStubCurler()
+		cfg = Config{
+			SpaceID:                "space-guid",
+			DrainName:              "space-drain",
+			DrainURL:               "syslog://logs.example.com:514",
+			DrainType:              "all",
+			ReconcileInterval:      time.Minute,
+			MaxReconcileBackoff:    10 * time.Minute,
+			AuditEventPollInterval: 10 * time.Millisecond,
+			HealthFailureThreshold: time.Minute,
+			Concurrency:            2,
+			VCAPApplication:        Application{ID: "drain-app-guid"},
+		}
+	})
+
+	Describe("nextInterval", func() {
+		It("waits the reconcile interval after a successful reconcile", func() {
+			r := newTestReconciler(curler, cfg)
+			Expect(r.nextInterval(0)).To(Equal(time.Minute))
+		})
+
+		It("doubles the interval for every consecutive failure", func() {
+			r := newTestReconciler(curler, cfg)
+			Expect(r.nextInterval(1)).To(Equal(2 * time.Minute))
+			Expect(r.nextInterval(2)).To(Equal(4 * time.Minute))
+			Expect(r.nextInterval(3)).To(Equal(8 * time.Minute))
+		})
+
+		It("caps the interval at the max backoff", func() {
+			r := newTestReconciler(curler, cfg)
+			Expect(r.nextInterval(4)).To(Equal(10 * time.Minute))
+			Expect(r.nextInterval(1000)).To(Equal(10 * time.Minute))
+		})
+
+		It("adds up to the jitter to the interval", func() {
+			cfg.ReconcileJitter = 10 * time.Second
+			r := newTestReconciler(curler, cfg)
+
+			intervals := make(map[time.Duration]bool)
+			for i := 0; i < 100; i++ {
+				interval := r.nextInterval(0)
+				Expect(interval).To(BeNumerically(">=", time.Minute))
+				Expect(interval).To(BeNumerically("<", time.Minute+10*time.Second))
+				intervals[interval] = true
+			}
+			Expect(len(intervals)).To(BeNumerically(">", 1))
+		})
+	})
+
+	Describe("run", func() {
+		It("reconciles every interval until the context is done", func() {
+			cfg.ReconcileInterval = 10 * time.Millisecond
+			cfg.MaxReconcileBackoff = 10 * time.Millisecond
+			r := newTestReconciler(curler, cfg)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				r.run(ctx)
+			}()
+
+			Eventually(func() int {
+				return curler.requestsTo("GET", "/v3/apps")
+			}).Should(BeNumerically(">", 1))
+			Expect(r.status.healthy()).To(BeTrue())
+
+			cancel()
+			Eventually(done).Should(BeClosed())
+
+			reconciles := curler.requestsTo("GET", "/v3/apps")
+			Consistently(func() int {
+				return curler.requestsTo("GET", "/v3/apps")
+			}, 50*time.Millisecond).Should(Equal(reconciles))
+		})
+
+		It("records failed reconciles and keeps reconciling", func() {
+			cfg.ReconcileInterval = 10 * time.Millisecond
+			cfg.MaxReconcileBackoff = 10 * time.Millisecond
+			curler.errs["/v3/service_instances"] = fmt.Errorf("some-error")
+			r := newTestReconciler(curler, cfg)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go r.run(ctx)
+
+			Eventually(func() int {
+				return curler.requestsTo("GET", "/v3/service_instances")
+			}).Should(BeNumerically(">", 1))
+			Expect(r.status.response().LastError).To(Equal("failed to fetch drains: some-error"))
+			Expect(r.status.response().FailingSince).ToNot(BeNil())
+		})
+
+		It("returns while waiting for the next reconcile", func() {
+			r := newTestReconciler(curler, cfg)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				r.run(ctx)
+			}()
+
+			Eventually(func() *time.Time {
+				return r.status.response().LastReconcile
+			}).ShouldNot(BeNil())
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Describe("pollEvents", func() {
+		var (
+			r      *reconciler
+			ctx    context.Context
+			cancel context.CancelFunc
+			done   chan struct{}
+			since  time.Time
+		)
+
+		BeforeEach(func() {
+			since = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			curler.addDrain("space-guid", stubDrain{
+				guid: "drain-guid",
+				name: "space-drain",
+				url:  "syslog://logs.example.com:514?drain-type=all",
+			})
+		})
+
+		JustBeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+			done = make(chan struct{})
+			r = newTestReconciler(curler, cfg)
+			go func() {
+				defer close(done)
+				r.pollEvents(ctx, since)
+			}()
+		})
+
+		AfterEach(func() {
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("binds apps of new create events", func() {
+			curler.addEvent(stubEvent{guid: "event-1", createdAt: since.Add(time.Second), spaceGuid: "space-guid", appGuid: "app-1", appName: "app-1"})
+
+			Eventually(curler.boundApps).Should(ConsistOf("app-1"))
+		})
+
+		It("handles every event once although it is fetched again", func() {
+			curler.addEvent(stubEvent{guid: "event-1", createdAt: since.Add(time.Second), spaceGuid: "space-guid", appGuid: "app-1", appName: "app-1"})
+			curler.addEvent(stubEvent{guid: "event-2", createdAt: since.Add(time.Second), spaceGuid: "space-guid", appGuid: "app-2", appName: "app-2"})
+
+			Eventually(curler.boundApps).Should(ConsistOf("app-1", "app-2"))
+			Eventually(curler.eventRequests).Should(BeNumerically(">", 3))
+			Expect(curler.envReads("app-1")).To(Equal(1))
+			Expect(curler.envReads("app-2")).To(Equal(1))
+
+			curler.addEvent(stubEvent{guid: "event-3", createdAt: since.Add(2 * time.Second), spaceGuid: "space-guid", appGuid: "app-3", appName: "app-3"})
+			Eventually(curler.boundApps).Should(ConsistOf("app-1", "app-2", "app-3"))
+			Expect(curler.envReads("app-1")).To(Equal(1))
+		})
+
+		It("requests events from the time of the last seen event", func() {
+			curler.addEvent(stubEvent{guid: "event-1", createdAt: since.Add(time.Minute), spaceGuid: "space-guid", appGuid: "app-1", appName: "app-1"})
+
+			Eventually(curler.lastEventsSince).Should(Equal(since.Add(time.Minute)))
+		})
+
+		Context("when apps are bound already", func() {
+			BeforeEach(func() {
+				curler.drains["space-guid"][0].apps = []string{"app-0"}
+			})
+
+			It("records every app bound to the drain", func() {
+				curler.addEvent(stubEvent{guid: "event-1", createdAt: since.Add(time.Second), spaceGuid: "space-guid", appGuid: "app-1", appName: "app-1"})
+
+				Eventually(func() int {
+					return r.status.response().AppsBound
+				}).Should(Equal(2))
+				Expect(r.metrics.apps.Value("space-guid", "bound")).To(Equal(2.0))
+			})
+		})
+
+		Context("with an org scope", func() {
+			BeforeEach(func() {
+				cfg.DrainScope = "org"
+				cfg.OrgID = "org-guid"
+				curler.addDrain("other-space-guid", stubDrain{
+					guid: "other-drain-guid",
+					name: "space-drain",
+					url:  "syslog://logs.example.com:514?drain-type=all",
+				})
+			})
+
+			It("binds apps to the drain in the space of their event", func() {
+				curler.addEvent(stubEvent{guid: "event-1", createdAt: since.Add(time.Second), spaceGuid: "space-guid", appGuid: "app-1", appName: "app-1"})
+				curler.addEvent(stubEvent{guid: "event-2", createdAt: since.Add(time.Second), spaceGuid: "other-space-guid", appGuid: "app-2", appName: "app-2"})
+
+				Eventually(func() []string {
+					return curler.drainApps("space-guid")
+				}).Should(ConsistOf("app-1"))
+				Eventually(func() []string {
+					return curler.drainApps("other-space-guid")
+				}).Should(ConsistOf("app-2"))
+			})
+		})
+
+		Context("when the drain does not exist yet", func() {
+			BeforeEach(func() {
+				curler.drains = make(map[string][]stubDrain)
+			})
+
+			It("leaves the apps to the next reconcile", func() {
+				curler.addEvent(stubEvent{guid: "event-1", createdAt: since.Add(time.Second), spaceGuid: "space-guid", appGuid: "app-1", appName: "app-1"})
+
+				Eventually(curler.eventRequests).Should(BeNumerically(">", 1))
+				Consistently(curler.boundApps, 50*time.Millisecond).Should(BeEmpty())
+			})
+		})
+	})
+
+	Describe("parallel", func() {
+		It("calls f for every app from at most CONCURRENCY goroutines", func() {
+			cfg.Concurrency = 3
+			r := newTestReconciler(curler, cfg)
+
+			var apps []cloudcontroller.App
+			for i := 0; i < 20; i++ {
+				apps = append(apps, cloudcontroller.App{Guid: fmt.Sprintf("app-%d", i)})
+			}
+
+			var (
+				mu            sync.Mutex
+				called        []string
+				running       int
+				maxConcurrent int
+			)
+			r.parallel(apps, func(app cloudcontroller.App) {
+				mu.Lock()
+				called = append(called, app.Guid)
+				running++
+				if running > maxConcurrent {
+					maxConcurrent = running
+				}
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+			})
+
+			Expect(called).To(HaveLen(20))
+			Expect(maxConcurrent).To(BeNumerically(">", 1))
+			Expect(maxConcurrent).To(BeNumerically("<=", 3))
+		})
+
+		It("returns right away without apps", func() {
+			r := newTestReconciler(curler, cfg)
+
+			var called bool
+			r.parallel(nil, func(cloudcontroller.App) {
+				called = true
+			})
+			Expect(called).To(BeFalse())
+		})
+	})
+
+	Describe("reconcile", func() {
+		It("creates the drain and binds every app in the space", func() {
+			curler.apps["space-guid"] = []cloudcontroller.App{
+				{Name: "app-1", Guid: "app-1"},
+				{Name: "app-2", Guid: "app-2"},
+			}
+
+			r := newTestReconciler(curler, cfg)
+			Expect(r.reconcile()).To(Succeed())
+
+			drains := curler.drains["space-guid"]
+			Expect(drains).To(HaveLen(1))
+			Expect(drains[0].name).To(Equal("space-drain"))
+			Expect(drains[0].url).To(Equal("syslog://logs.example.com:514?drain-type=all"))
+			Expect(drains[0].tags).To(Equal([]string{"space-drain:drain-app-guid"}))
+			Expect(curler.boundApps()).To(ConsistOf("app-1", "app-2"))
+			Expect(r.status.response().AppsBound).To(Equal(2))
+		})
+
+		It("never binds itself or other drain apps", func() {
+			curler.apps["space-guid"] = []cloudcontroller.App{
+				{Name: "drain-app", Guid: "drain-app-guid"},
+				{Name: "org-drain", Guid: "org-drain-guid"},
+				{Name: "app-1", Guid: "app-1"},
+			}
+			curler.envs["org-drain-guid"] = map[string]string{"DRAIN_SCOPE": "org"}
+
+			r := newTestReconciler(curler, cfg)
+			Expect(r.reconcile()).To(Succeed())
+
+			Expect(curler.boundApps()).To(ConsistOf("app-1"))
+		})
+
+		It("reads the env of an app once while its scope is cached", func() {
+			cfg.DrainScopeCacheTTL = time.Minute
+			curler.apps["space-guid"] = []cloudcontroller.App{
+				{Name: "org-drain", Guid: "org-drain-guid"},
+			}
+			curler.envs["org-drain-guid"] = map[string]string{"DRAIN_SCOPE": "org"}
+
+			r := newTestReconciler(curler, cfg)
+			Expect(r.reconcile()).To(Succeed())
+			Expect(r.reconcile()).To(Succeed())
+
+			Expect(curler.envReads("org-drain-guid")).To(Equal(1))
+		})
+
+		It("reads the env of an app again once its scope expired", func() {
+			cfg.DrainScopeCacheTTL = time.Minute
+			curler.apps["space-guid"] = []cloudcontroller.App{
+				{Name: "org-drain", Guid: "org-drain-guid"},
+			}
+			curler.envs["org-drain-guid"] = map[string]string{"DRAIN_SCOPE": "org"}
+
+			r := newTestReconciler(curler, cfg)
+			Expect(r.reconcile()).To(Succeed())
+			r.scopes.entries["org-drain-guid"] = scopeEntry{drainApp: true, expires: time.Now().Add(-time.Second)}
+			Expect(r.reconcile()).To(Succeed())
+
+			Expect(curler.envReads("org-drain-guid")).To(Equal(2))
+		})
+
+		It("does not bind apps that are bound already", func() {
+			curler.addDrain("space-guid", stubDrain{
+				guid: "drain-guid",
+				name: "space-drain",
+				url:  "syslog://logs.example.com:514?drain-type=all",
+				apps: []string{"app-1"},
+			})
+			curler.apps["space-guid"] = []cloudcontroller.App{
+				{Name: "app-1", Guid: "app-1"},
+				{Name: "app-2", Guid: "app-2"},
+			}
+
+			r := newTestReconciler(curler, cfg)
+			Expect(r.reconcile()).To(Succeed())
+
+			Expect(curler.requestsTo("POST", "/v2/service_bindings")).To(Equal(1))
+			Expect(curler.boundApps()).To(ConsistOf("app-1", "app-2"))
+		})
+
+		It("records apps that failed to bind", func() {
+			curler.apps["space-guid"] = []cloudcontroller.App{
+				{Name: "app-1", Guid: "app-1"},
+				{Name: "app-2", Guid: "app-2"},
+			}
+			curler.bindErrs["app-2"] = fmt.Errorf("bind-error")
+
+			r := newTestReconciler(curler, cfg)
+			Expect(r.reconcile()).To(Succeed())
+
+			Expect(r.status.response().AppsBound).To(Equal(1))
+			Expect(r.status.response().FailedBinds).To(Equal(map[string]string{"app-2": "bind-error"}))
+		})
+
+		Context("when pruning bindings", func() {
+			BeforeEach(func() {
+				cfg.PruneBindings = true
+				curler.addDrain("space-guid", stubDrain{
+					guid: "drain-guid",
+					name: "space-drain",
+					url:  "syslog://logs.example.com:514?drain-type=all",
+					tags: []string{"space-drain:drain-app-guid"},
+					apps: []string{"app-1", "app-2", "deleted-app"},
+				})
+				curler.apps["space-guid"] = []cloudcontroller.App{
+					{Name: "app-1", Guid: "app-1"},
+					{Name: "app-2", Guid: "app-2"},
+				}
+			})
+
+			It("unbinds apps that left the scope of the drain", func() {
+				curler.envs["app-2"] = map[string]string{"DRAIN_SCOPE": "space"}
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.boundApps()).To(ConsistOf("app-1"))
+				Expect(r.status.response().AppsBound).To(Equal(1))
+			})
+
+			It("keeps apps whose scope can not be read", func() {
+				curler.errs["/v3/apps/app-2/env"] = fmt.Errorf("some-error")
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.boundApps()).To(ConsistOf("app-1", "app-2"))
+			})
+
+			It("does not unbind apps without pruning", func() {
+				cfg.PruneBindings = false
+				curler.envs["app-2"] = map[string]string{"DRAIN_SCOPE": "space"}
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.boundApps()).To(ConsistOf("app-1", "app-2", "deleted-app"))
+				Expect(curler.envReads("app-2")).To(Equal(0))
+			})
+
+			It("removes drains it created under a previous name", func() {
+				curler.addDrain("space-guid", stubDrain{
+					guid: "old-drain-guid",
+					name: "old-space-drain",
+					url:  "syslog://logs.example.com:514?drain-type=all",
+					tags: []string{"space-drain:drain-app-guid"},
+					apps: []string{"app-1"},
+				})
+				curler.addDrain("space-guid", stubDrain{
+					guid: "other-drain-guid",
+					name: "other-space-drain",
+					url:  "syslog://logs.example.com:514?drain-type=all",
+					tags: []string{"space-drain:other-app-guid"},
+					apps: []string{"app-1"},
+				})
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				var names []string
+				for _, d := range curler.drains["space-guid"] {
+					names = append(names, d.name)
+				}
+				Expect(names).To(ConsistOf("space-drain", "other-space-drain"))
+				Expect(curler.requestsTo("DELETE", "/v3/service_credential_bindings/old-drain-guid:app-1")).To(Equal(1))
+			})
+
+			It("recreates the drain when its URL changed", func() {
+				cfg.DrainURL = "syslog://new-logs.example.com:514"
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				drains := curler.drains["space-guid"]
+				Expect(drains).To(HaveLen(1))
+				Expect(drains[0].guid).To(Equal("created-drain-1"))
+				Expect(drains[0].url).To(Equal("syslog://new-logs.example.com:514?drain-type=all"))
+				Expect(drains[0].apps).To(ConsistOf("app-1", "app-2"))
+				Expect(curler.requestsTo("DELETE", "/v3/service_instances/drain-guid")).To(Equal(1))
+			})
+
+			It("does not recreate the drain if only the order of query parameters differs", func() {
+				cfg.DrainURL = "syslog://logs.example.com:514?b=2&a=1"
+				curler.drains["space-guid"][0].url = "syslog://logs.example.com:514?drain-type=all&a=1&b=2"
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.requestsTo("DELETE", "/v3/service_instances/drain-guid")).To(Equal(0))
+			})
+
+			It("fails if the changed drain can not be removed", func() {
+				cfg.DrainURL = "syslog://new-logs.example.com:514"
+				curler.errs["/v3/service_instances/drain-guid"] = fmt.Errorf("some-error")
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(MatchError("failed to delete space-drain drain: some-error"))
+			})
+		})
+
+		Context("with include and exclude filters", func() {
+			BeforeEach(func() {
+				curler.apps["space-guid"] = []cloudcontroller.App{
+					{Name: "orders", Guid: "app-1"},
+					{Name: "billing", Guid: "app-2"},
+					{Name: "load-generator", Guid: "app-3"},
+				}
+			})
+
+			It("only binds apps matching the include selector", func() {
+				cfg.IncludeLabelSelector = "team=orders"
+				curler.labeled["team=orders"] = []cloudcontroller.App{{Name: "orders", Guid: "app-1"}}
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.boundApps()).To(ConsistOf("app-1"))
+			})
+
+			It("does not bind apps matching the exclude selector", func() {
+				cfg.ExcludeLabelSelector = "load-generator=true"
+				curler.labeled["load-generator=true"] = []cloudcontroller.App{{Name: "load-generator", Guid: "app-3"}}
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.boundApps()).To(ConsistOf("app-1", "app-2"))
+			})
+
+			It("does not bind apps whose name matches the exclude regex", func() {
+				r := newTestReconciler(curler, cfg)
+				r.excludeAppName = regexp.MustCompile("^load-")
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.boundApps()).To(ConsistOf("app-1", "app-2"))
+			})
+
+			It("applies the filters to apps of create events", func() {
+				cfg.ExcludeLabelSelector = "load-generator=true"
+				curler.labeled["load-generator=true"] = []cloudcontroller.App{{Name: "load-generator", Guid: "app-3"}}
+				curler.addDrain("space-guid", stubDrain{
+					guid: "drain-guid",
+					name: "space-drain",
+					url:  "syslog://logs.example.com:514?drain-type=all",
+				})
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.bindApps("space-guid", []cloudcontroller.App{
+					{Name: "billing", Guid: "app-2"},
+					{Name: "load-generator", Guid: "app-3"},
+				})).To(Succeed())
+
+				Expect(curler.boundApps()).To(ConsistOf("app-2"))
+			})
+
+			It("unbinds apps that no longer match the filters when pruning", func() {
+				cfg.PruneBindings = true
+				cfg.ExcludeLabelSelector = "load-generator=true"
+				curler.labeled["load-generator=true"] = []cloudcontroller.App{{Name: "load-generator", Guid: "app-3"}}
+				curler.addDrain("space-guid", stubDrain{
+					guid: "drain-guid",
+					name: "space-drain",
+					url:  "syslog://logs.example.com:514?drain-type=all",
+					apps: []string{"app-3"},
+				})
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.boundApps()).To(ConsistOf("app-1", "app-2"))
+			})
+
+			It("fails if the included apps can not be listed", func() {
+				cfg.IncludeLabelSelector = "team=orders"
+				curler.addDrain("space-guid", stubDrain{
+					guid: "drain-guid",
+					name: "space-drain",
+					url:  "syslog://logs.example.com:514?drain-type=all",
+				})
+
+				curler.errs["/v3/apps?label_selector=team%3Dorders&space_guids=space-guid"] = fmt.Errorf("some-error")
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(MatchError("failed to list included apps: some-error"))
+				Expect(curler.boundApps()).To(BeEmpty())
+			})
+		})
+
+		Context("with an org scope", func() {
+			BeforeEach(func() {
+				cfg.DrainScope = "org"
+				cfg.OrgID = "org-guid"
+				curler.spaces = []cloudcontroller.Space{
+					{Name: "space-1", Guid: "space-1-guid"},
+					{Name: "space-2", Guid: "space-2-guid"},
+				}
+				curler.apps["space-1-guid"] = []cloudcontroller.App{{Name: "app-1", Guid: "app-1"}}
+				curler.apps["space-2-guid"] = []cloudcontroller.App{{Name: "app-2", Guid: "app-2"}}
+			})
+
+			It("creates a drain in every space of the org", func() {
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+
+				Expect(curler.drainApps("space-1-guid")).To(ConsistOf("app-1"))
+				Expect(curler.drainApps("space-2-guid")).To(ConsistOf("app-2"))
+			})
+
+			It("forgets the apps bound in spaces that left the org", func() {
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(Succeed())
+				Expect(r.status.response().AppsBound).To(Equal(2))
+
+				curler.spaces = curler.spaces[:1]
+				Expect(r.reconcile()).To(Succeed())
+				Expect(r.status.response().AppsBound).To(Equal(1))
+
+				out := &bytes.Buffer{}
+				Expect(r.metrics.registry.WriteText(out)).To(Succeed())
+				Expect(out.String()).To(ContainSubstring(`space_drain_apps{space="space-1-guid",state="bound"} 1`))
+				Expect(out.String()).ToNot(ContainSubstring("space-2-guid"))
+			})
+
+			It("reconciles the other spaces if a space fails", func() {
+				curler.errs["/v3/service_instances?space_guids=space-1-guid&type=user-provided"] = fmt.Errorf("some-error")
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(MatchError("failed to reconcile 1 of 2 spaces"))
+
+				Expect(curler.drainApps("space-2-guid")).To(ConsistOf("app-2"))
+			})
+
+			It("fails if the spaces can not be listed", func() {
+				curler.errs["/v3/spaces"] = fmt.Errorf("some-error")
+
+				r := newTestReconciler(curler, cfg)
+				Expect(r.reconcile()).To(MatchError("failed to list spaces: some-error"))
+			})
+		})
+
+		It("fails if the drains can not be listed", func() {
+			curler.errs["/v3/service_instances"] = fmt.Errorf("some-error")
+
+			r := newTestReconciler(curler, cfg)
+			Expect(r.reconcile()).To(MatchError("failed to fetch drains: some-error"))
+		})
+	})
+})
+
+func newTestReconciler(c *stubCurler, cfg Config) *reconciler {
+	return &reconciler{
+		drainLister: drain.NewServiceDrainLister(c),
+		drainCreator: cloudcontroller.NewCreateDrainClient(
+			c,
+			cloudcontroller.WithCreateDrainTags(drain.SpaceDrainTag(cfg.VCAPApplication.ID)),
+		),
+		drainBinder:   cloudcontroller.NewBindDrainClient(c),
+		drainUnbinder: cloudcontroller.NewUnbindDrainClient(c),
+		drainDeleter:  cloudcontroller.NewDeleteDrainClient(c),
+		appLister:     cloudcontroller.NewAppListerClient(c),
+		spaceLister:   cloudcontroller.NewSpaceListerClient(c),
+		eventClient:   cloudcontroller.NewAuditEventClient(c),
+		curler:        c,
+		scopes:        newScopeCache(cfg.DrainScopeCacheTTL),
+		status:        newStatus(cfg.HealthFailureThreshold, true),
+		metrics:       newDrainMetrics(),
+		cfg:           cfg,
+		log:           log.New(GinkgoWriter, "", 0),
+	}
+}
+
+// stubCurler is a Cloud Controller that keeps the drains, bindings, apps
+// and audit events the clients of the reconciler request.
+type stubCurler struct {
+	mu sync.Mutex
+
+	spaces  []cloudcontroller.Space
+	drains  map[string][]stubDrain
+	apps    map[string][]cloudcontroller.App
+	labeled map[string][]cloudcontroller.App
+	envs    map[string]map[string]string
+	events  []stubEvent
+
+	// errs are returned for requests to the path or URL.
+	errs      map[string]error
+	bindErrs  map[string]error
+	requests  []string
+	drainSeq  int
+	eventsGte time.Time
+}
+
+type stubDrain struct {
+	guid string
+	name string
+	url  string
+	tags []string
+	apps []string
+}
+
+type stubEvent struct {
+	guid      string
+	createdAt time.Time
+	spaceGuid string
+	appGuid   string
+	appName   string
+}
+
+func newStubCurler() *stubCurler {
+	return &stubCurler{
+		drains:   make(map[string][]stubDrain),
+		apps:     make(map[string][]cloudcontroller.App),
+		labeled:  make(map[string][]cloudcontroller.App),
+		envs:     make(map[string]map[string]string),
+		errs:     make(map[string]error),
+		bindErrs: make(map[string]error),
+	}
+}
+
+func (s *stubCurler) addDrain(spaceGuid string, d stubDrain) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.drains[spaceGuid] = append(s.drains[spaceGuid], d)
+}
+
+func (s *stubCurler) addEvent(e stubEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, e)
+}
+
+// drainApps returns the GUIDs of the apps bound to the drains in the space.
+func (s *stubCurler) drainApps(spaceGuid string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var apps []string
+	for _, d := range s.drains[spaceGuid] {
+		apps = append(apps, d.apps...)
+	}
+	return apps
+}
+
+// boundApps returns the GUIDs of the apps bound to any drain.
+func (s *stubCurler) boundApps() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var apps []string
+	for _, drains := range s.drains {
+		for _, d := range drains {
+			apps = append(apps, d.apps...)
+		}
+	}
+	return apps
+}
+
+func (s *stubCurler) requestsTo(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for _, r := range s.requests {
+		if r == method+" "+path || strings.HasPrefix(r, method+" "+path+"?") {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *stubCurler) envReads(appGuid string) int {
+	return s.requestsTo("GET", "/v3/apps/"+appGuid+"/env")
+}
+
+func (s *stubCurler) eventRequests() int {
+	return s.requestsTo("GET", "/v3/audit_events")
+}
+
+func (s *stubCurler) lastEventsSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.eventsGte
+}
+
+func (s *stubCurler) Curl(URL, method, body string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, method+" "+URL)
+
+	u, err := url.Parse(URL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.errs[u.Path]; err != nil {
+		return nil, err
+	}
+	if err := s.errs[URL]; err != nil {
+		return nil, err
+	}
+	q := u.Query()
+
+	switch {
+	case method == "GET" && u.Path == "/v3/service_instances":
+		return s.serviceInstances(q.Get("space_guids"))
+	case method == "GET" && u.Path == "/v3/service_credential_bindings":
+		return s.bindings(q)
+	case method == "DELETE" && strings.HasPrefix(u.Path, "/v3/service_credential_bindings/"):
+		s.unbind(strings.TrimPrefix(u.Path, "/v3/service_credential_bindings/"))
+		return nil, nil
+	case method == "DELETE" && strings.HasPrefix(u.Path, "/v3/service_instances/"):
+		s.deleteDrain(strings.TrimPrefix(u.Path, "/v3/service_instances/"))
+		return nil, nil
+	case method == "POST" && u.Path == "/v2/user_provided_service_instances":
+		return nil, s.createDrain(body)
+	case method == "POST" && u.Path == "/v2/service_bindings":
+		return nil, s.bind(body)
+	case method == "GET" && u.Path == "/v3/apps":
+		apps := s.apps[q.Get("space_guids")]
+		if selector := q.Get("label_selector"); selector != "" {
+			apps = s.labeled[selector]
+		}
+		return appsResponse(apps)
+	case method == "GET" && strings.HasSuffix(u.Path, "/env"):
+		appGuid := strings.TrimSuffix(strings.TrimPrefix(u.Path, "/v3/apps/"), "/env")
+		return json.Marshal(map[string]interface{}{"environment_variables": s.envs[appGuid]})
+	case method == "GET" && u.Path == "/v3/spaces":
+		return s.spacesResponse()
+	case method == "GET" && u.Path == "/v3/audit_events":
+		return s.eventsResponse(q.Get("created_ats[gte]"))
+	}
+
+	return nil, fmt.Errorf("unexpected request %s %s", method, URL)
+}
+
+func (s *stubCurler) serviceInstances(spaceGuid string) ([]byte, error) {
+	var resources []map[string]interface{}
+	for _, d := range s.drains[spaceGuid] {
+		resources = append(resources, map[string]interface{}{
+			"guid":             d.guid,
+			"name":             d.name,
+			"syslog_drain_url": d.url,
+			"tags":             d.tags,
+		})
+	}
+	return json.Marshal(map[string]interface{}{"resources": resources})
+}
+
+func (s *stubCurler) bindings(q url.Values) ([]byte, error) {
+	instances := strings.Split(q.Get("service_instance_guids"), ",")
+	appGuid := q.Get("app_guids")
+
+	var resources []map[string]interface{}
+	for _, drains := range s.drains {
+		for _, d := range drains {
+			if !containsApp(d.guid, instances) {
+				continue
+			}
+			for _, a := range d.apps {
+				if appGuid != "" && a != appGuid {
+					continue
+				}
+				resources = append(resources, map[string]interface{}{
+					"guid": d.guid + ":" + a,
+					"relationships": map[string]interface{}{
+						"app":              map[string]interface{}{"data": map[string]string{"guid": a}},
+						"service_instance": map[string]interface{}{"data": map[string]string{"guid": d.guid}},
+					},
+				})
+			}
+		}
+	}
+	return json.Marshal(map[string]interface{}{"resources": resources})
+}
+
+func (s *stubCurler) unbind(bindingGuid string) {
+	parts := strings.SplitN(bindingGuid, ":", 2)
+	s.updateDrain(parts[0], func(d *stubDrain) {
+		var apps []string
+		for _, a := range d.apps {
+			if a != parts[1] {
+				apps = append(apps, a)
+			}
+		}
+		d.apps = apps
+	})
+}
+
+func (s *stubCurler) deleteDrain(guid string) {
+	for space, drains := range s.drains {
+		var kept []stubDrain
+		for _, d := range drains {
+			if d.guid != guid {
+				kept = append(kept, d)
+			}
+		}
+		s.drains[space] = kept
+	}
+}
+
+func (s *stubCurler) createDrain(body string) error {
+	var req struct {
+		Name           string   `json:"name"`
+		SpaceGuid      string   `json:"space_guid"`
+		SyslogDrainURL string   `json:"syslog_drain_url"`
+		Tags           []string `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		return err
+	}
+
+	s.drainSeq++
+	s.drains[req.SpaceGuid] = append(s.drains[req.SpaceGuid], stubDrain{
+		guid: fmt.Sprintf("created-drain-%d", s.drainSeq),
+		name: req.Name,
+		url:  req.SyslogDrainURL,
+		tags: req.Tags,
+	})
+	return nil
+}
+
+func (s *stubCurler) bind(body string) error {
+	var req struct {
+		ServiceInstanceGuid string `json:"service_instance_guid"`
+		AppGuid             string `json:"app_guid"`
+	}
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		return err
+	}
+	if err := s.bindErrs[req.AppGuid]; err != nil {
+		return err
+	}
+
+	s.updateDrain(req.ServiceInstanceGuid, func(d *stubDrain) {
+		d.apps = append(d.apps, req.AppGuid)
+	})
+	return nil
+}
+
+func (s *stubCurler) updateDrain(guid string, f func(*stubDrain)) {
+	for _, drains := range s.drains {
+		for i := range drains {
+			if drains[i].guid == guid {
+				f(&drains[i])
+			}
+		}
+	}
+}
+
+func appsResponse(apps []cloudcontroller.App) ([]byte, error) {
+	var resources []map[string]string
+	for _, a := range apps {
+		resources = append(resources, map[string]string{"guid": a.Guid, "name": a.Name})
+	}
+	return json.Marshal(map[string]interface{}{"resources": resources})
+}
+
+func (s *stubCurler) spacesResponse() ([]byte, error) {
+	var resources []map[string]string
+	for _, sp := range s.spaces {
+		resources = append(resources, map[string]string{"guid": sp.Guid, "name": sp.Name})
+	}
+	return json.Marshal(map[string]interface{}{"resources": resources})
+}
+
+// eventsResponse returns the events created at or after gte like Cloud
+// Controller does, so events are fetched again by the next poll.
+func (s *stubCurler) eventsResponse(gte string) ([]byte, error) {
+	since, err := time.Parse(time.RFC3339, gte)
+	if err != nil {
+		return nil, err
+	}
+	s.eventsGte = since
+
+	var resources []map[string]interface{}
+	for _, e := range s.events {
+		if e.createdAt.Before(since) {
+			continue
+		}
+		resources = append(resources, map[string]interface{}{
+			"guid":       e.guid,
+			"created_at": e.createdAt,
+			"space":      map[string]string{"guid": e.spaceGuid},
+			"target":     map[string]string{"guid": e.appGuid, "name": e.appName},
+		})
+	}
+	return json.Marshal(map[string]interface{}{"resources": resources})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// scopeCache remembers which apps are drain apps so their env vars are not
+// read on every reconcile. Entries expire so an app that becomes a drain
+// after it was pushed, e.g. by `cf set-env`, is picked up eventually.
+type scopeCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]scopeEntry
+}
+
+type scopeEntry struct {
+	drainApp bool
+	expires  time.Time
+}
+
+// newScopeCache creates a cache whose entries expire after the ttl. A ttl of
+// zero disables the cache.
+func newScopeCache(ttl time.Duration) *scopeCache {
+	return &scopeCache{
+		ttl:     ttl,
+		entries: make(map[string]scopeEntry),
+	}
+}
+
+func (c *scopeCache) get(appGuid string) (drainApp bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[appGuid]
+	if !ok || time.Now().After(e.expires) {
+		return false, false
+	}
+
+	return e.drainApp, true
+}
+
+func (c *scopeCache) set(appGuid string, drainApp bool) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[appGuid] = scopeEntry{
+		drainApp: drainApp,
+		expires:  time.Now().Add(c.ttl),
+	}
+}
+
+// expire removes the expired entries, e.g. of deleted apps.
+func (c *scopeCache) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for guid, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, guid)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("scopeCache", func() {
+	It("remembers whether an app is a drain app", func() {
+		c := newScopeCache(time.Minute)
+		c.set("drain-app", true)
+		c.set("app", false)
+
+		drainApp, ok := c.get("drain-app")
+		Expect(ok).To(BeTrue())
+		Expect(drainApp).To(BeTrue())
+
+		drainApp, ok = c.get("app")
+		Expect(ok).To(BeTrue())
+		Expect(drainApp).To(BeFalse())
+
+		_, ok = c.get("unknown-app")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("forgets entries after the ttl", func() {
+		c := newScopeCache(10 * time.Millisecond)
+		c.set("drain-app", true)
+
+		Eventually(func() bool {
+			_, ok := c.get("drain-app")
+			return ok
+		}).Should(BeFalse())
+	})
+
+	It("does not cache anything with a ttl of zero", func() {
+		c := newScopeCache(0)
+		c.set("drain-app", true)
+
+		_, ok := c.get("drain-app")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("removes expired entries", func() {
+		c := newScopeCache(time.Minute)
+		c.set("drain-app", true)
+		c.set("deleted-app", false)
+		c.entries["deleted-app"] = scopeEntry{expires: time.Now().Add(-time.Second)}
+
+		c.expire()
+
+		Expect(c.entries).To(HaveLen(1))
+		Expect(c.entries).To(HaveKey("drain-app"))
+	})
+})
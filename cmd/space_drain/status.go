@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+)
+
+// status records the outcome of reconciles, binds and token refreshes so
+// they can be served on /status and /health.
+type status struct {
+	mu sync.Mutex
+
+	failureThreshold time.Duration
+	leader           bool
+	started          time.Time
+
+	lastReconcile    time.Time
+	lastSuccess      time.Time
+	lastError        string
+	failingSince     time.Time
+	appsBound        map[string]int
+	failedBinds      map[string]string
+	lastTokenRefresh time.Time
+	lastTokenError   string
+}
+
+func newStatus(failureThreshold time.Duration, leader bool) *status {
+	return &status{
+		failureThreshold: failureThreshold,
+		leader:           leader,
+		started:          time.Now(),
+		appsBound:        make(map[string]int),
+		failedBinds:      make(map[string]string),
+	}
+}
+
+// reconcileStarted forgets the failed binds of earlier reconciles. Every
+// app is retried by the reconcile.
+func (s *status) reconcileStarted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failedBinds = make(map[string]string)
+}
+
+func (s *status) reconciled(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastReconcile = now
+	if err == nil {
+		s.lastSuccess = now
+		s.lastError = ""
+		s.failingSince = time.Time{}
+		return
+	}
+
+	s.lastError = err.Error()
+	if s.failingSince.IsZero() {
+		s.failingSince = now
+	}
+}
+
+// bound records the number of apps bound to the drain in the space.
+func (s *status) bound(spaceID string, apps int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appsBound[spaceID] = apps
+}
+
+// retainSpaces forgets the apps bound in every space but the given ones,
+// e.g. in spaces that were deleted from the org. It returns the forgotten
+// spaces.
+func (s *status) retainSpaces(spaceIDs []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	appsBound := make(map[string]int, len(spaceIDs))
+	for _, id := range spaceIDs {
+		if n, ok := s.appsBound[id]; ok {
+			appsBound[id] = n
+		}
+	}
+
+	var forgotten []string
+	for id := range s.appsBound {
+		if _, ok := appsBound[id]; !ok {
+			forgotten = append(forgotten, id)
+		}
+	}
+	s.appsBound = appsBound
+
+	return forgotten
+}
+
+func (s *status) bindSucceeded(appGuid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failedBinds, appGuid)
+}
+
+func (s *status) bindFailed(appGuid string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failedBinds[appGuid] = err.Error()
+}
+
+func (s *status) tokenRefreshed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.lastTokenError = err.Error()
+		return
+	}
+	s.lastTokenRefresh = time.Now()
+	s.lastTokenError = ""
+}
+
+// healthy reports whether reconciles have been failing for no longer than
+// the failure threshold. The leader is also unhealthy if it has not
+// finished a reconcile within the threshold, e.g. because one hangs.
+func (s *status) healthy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.failingSince.IsZero() && time.Since(s.failingSince) > s.failureThreshold {
+		return false
+	}
+
+	if !s.leader {
+		return true
+	}
+
+	lastReconcile := s.lastReconcile
+	if lastReconcile.IsZero() {
+		lastReconcile = s.started
+	}
+	return time.Since(lastReconcile) <= s.failureThreshold
+}
+
+type statusResponse struct {
+	Healthy                 bool              `json:"healthy"`
+	Leader                  bool              `json:"leader"`
+	LastReconcile           *time.Time        `json:"last_reconcile,omitempty"`
+	LastSuccessfulReconcile *time.Time        `json:"last_successful_reconcile,omitempty"`
+	LastError               string            `json:"last_error,omitempty"`
+	FailingSince            *time.Time        `json:"failing_since,omitempty"`
+	AppsBound               int               `json:"apps_bound"`
+	FailedBinds             map[string]string `json:"failed_binds"`
+	Token                   tokenStatus       `json:"token"`
+}
+
+type tokenStatus struct {
+	LastRefresh *time.Time `json:"last_refresh,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+func (s *status) response() statusResponse {
+	healthy := s.healthy()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var appsBound int
+	for _, n := range s.appsBound {
+		appsBound += n
+	}
+
+	failedBinds := make(map[string]string, len(s.failedBinds))
+	for app, reason := range s.failedBinds {
+		failedBinds[app] = reason
+	}
+
+	return statusResponse{
+		Healthy:                 healthy,
+		Leader:                  s.leader,
+		LastReconcile:           timeOrNil(s.lastReconcile),
+		LastSuccessfulReconcile: timeOrNil(s.lastSuccess),
+		LastError:               s.lastError,
+		FailingSince:            timeOrNil(s.failingSince),
+		AppsBound:               appsBound,
+		FailedBinds:             failedBinds,
+		Token: tokenStatus{
+			LastRefresh: timeOrNil(s.lastTokenRefresh),
+			LastError:   s.lastTokenError,
+		},
+	}
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// serveStatus writes the full status.
+func (s *status) serveStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.response())
+}
+
+// serveHealth writes the status with a 503 once reconciles have been
+// failing, or the leader has not reconciled, for longer than the failure
+// threshold.
+func (s *status) serveHealth(w http.ResponseWriter, r *http.Request) {
+	resp := s.response()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(struct {
+		Healthy                 bool       `json:"healthy"`
+		LastSuccessfulReconcile *time.Time `json:"last_successful_reconcile,omitempty"`
+		LastError               string     `json:"last_error,omitempty"`
+		FailingSince            *time.Time `json:"failing_since,omitempty"`
+	}{
+		Healthy:                 resp.Healthy,
+		LastSuccessfulReconcile: resp.LastSuccessfulReconcile,
+		LastError:               resp.LastError,
+		FailingSince:            resp.FailingSince,
+	})
+}
+
+// statusTokenFetcher records the outcome of every token refresh.
+type statusTokenFetcher struct {
+	f cloudcontroller.TokenFetcher
+	s *status
+}
+
+func (f statusTokenFetcher) Token() (string, string, error) {
+	accToken, refToken, err := f.f.Token()
+	f.s.tokenRefreshed(err)
+	return accToken, refToken, err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("status", func() {
+	var s *status
+
+	BeforeEach(func() {
+		s = newStatus(time.Minute, true)
+	})
+
+	health := func() (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		s.serveHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		var body map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &body)).To(Succeed())
+		return rec.Code, body
+	}
+
+	It("is healthy after a successful reconcile", func() {
+		s.reconciled(nil)
+
+		code, body := health()
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(HaveKeyWithValue("healthy", true))
+		Expect(body).To(HaveKey("last_successful_reconcile"))
+	})
+
+	It("stays healthy while reconciles fail for less than the threshold", func() {
+		s.reconciled(errors.New("some-error"))
+
+		code, body := health()
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(HaveKeyWithValue("last_error", "some-error"))
+		Expect(body).To(HaveKey("failing_since"))
+	})
+
+	It("responds with a 503 once reconciles fail for longer than the threshold", func() {
+		s.reconciled(errors.New("some-error"))
+		s.failingSince = time.Now().Add(-2 * time.Minute)
+
+		code, body := health()
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(body).To(HaveKeyWithValue("healthy", false))
+		Expect(body).To(HaveKeyWithValue("last_error", "some-error"))
+	})
+
+	It("becomes healthy again after a successful reconcile", func() {
+		s.reconciled(errors.New("some-error"))
+		s.failingSince = time.Now().Add(-2 * time.Minute)
+		s.reconciled(nil)
+
+		code, body := health()
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).ToNot(HaveKey("last_error"))
+		Expect(body).ToNot(HaveKey("failing_since"))
+	})
+
+	It("responds with a 503 if the leader has not reconciled within the threshold", func() {
+		s.reconciled(nil)
+		s.lastReconcile = time.Now().Add(-2 * time.Minute)
+
+		code, _ := health()
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("responds with a 503 if the leader never finished a reconcile", func() {
+		s.started = time.Now().Add(-2 * time.Minute)
+
+		code, _ := health()
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("does not expect a standby to reconcile", func() {
+		s = newStatus(time.Minute, false)
+		s.started = time.Now().Add(-2 * time.Minute)
+
+		code, _ := health()
+		Expect(code).To(Equal(http.StatusOK))
+	})
+
+	It("serves the full status", func() {
+		s.reconcileStarted()
+		s.bound("space-1", 2)
+		s.bound("space-2", 3)
+		s.bindFailed("app-1", errors.New("bind-error"))
+		s.bindFailed("app-2", errors.New("bind-error"))
+		s.bindSucceeded("app-2")
+		s.tokenRefreshed(nil)
+		s.tokenRefreshed(errors.New("token-error"))
+		s.reconciled(errors.New("some-error"))
+
+		rec := httptest.NewRecorder()
+		s.serveStatus(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var resp statusResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp.Healthy).To(BeTrue())
+		Expect(resp.Leader).To(BeTrue())
+		Expect(resp.LastReconcile).ToNot(BeNil())
+		Expect(resp.LastSuccessfulReconcile).To(BeNil())
+		Expect(resp.LastError).To(Equal("some-error"))
+		Expect(resp.FailingSince).ToNot(BeNil())
+		Expect(resp.AppsBound).To(Equal(5))
+		Expect(resp.FailedBinds).To(Equal(map[string]string{"app-1": "bind-error"}))
+		Expect(resp.Token.LastRefresh).ToNot(BeNil())
+		Expect(resp.Token.LastError).To(Equal("token-error"))
+	})
+
+	It("forgets the apps bound in spaces that are not retained", func() {
+		s.bound("space-1", 2)
+		s.bound("space-2", 3)
+		Expect(s.retainSpaces([]string{"space-1", "space-3"})).To(ConsistOf("space-2"))
+
+		Expect(s.response().AppsBound).To(Equal(2))
+		Expect(s.appsBound).To(Equal(map[string]int{"space-1": 2}))
+	})
+
+	It("forgets failed binds when a reconcile starts", func() {
+		s.bindFailed("app-1", errors.New("bind-error"))
+		s.reconcileStarted()
+
+		Expect(s.response().FailedBinds).To(BeEmpty())
+	})
+})
@@ -2,7 +2,7 @@ package cloudcontroller
 
 import (
 	"encoding/json"
-	"fmt"
+	"net/url"
 )
 
 type App struct {
@@ -24,35 +24,49 @@ func NewAppListerClient(c Curler) *AppListerClient {
 	}
 }
 
+// ListApps returns every app in the space.
 func (c *AppListerClient) ListApps(spaceGuid string) ([]App, error) {
-	resp, err := c.c.Curl(
-		fmt.Sprintf("/v2/apps?q=space_guid:%s", spaceGuid),
-		"GET",
-		"",
-	)
-
-	if err != nil {
-		return nil, err
-	}
+	return c.listApps(url.Values{
+		"space_guids": {spaceGuid},
+	})
+}
 
-	var apps struct {
-		Resources []struct {
-			Metadata struct {
-				Guid string
-			}
-			Entity struct {
-				Name string
-			}
-		}
-	}
-	err = json.Unmarshal(resp, &apps)
-	if err != nil {
-		return nil, err
-	}
+// ListAppsByLabel returns the apps in the space that match the given label
+// selector, e.g. "team=billing".
+func (c *AppListerClient) ListAppsByLabel(spaceGuid, labelSelector string) ([]App, error) {
+	return c.listApps(url.Values{
+		"space_guids":    {spaceGuid},
+		"label_selector": {labelSelector},
+	})
+}
 
+// listApps follows the pages of /v3/apps filtered by the given params.
+func (c *AppListerClient) listApps(params url.Values) ([]App, error) {
 	var a []App
-	for _, r := range apps.Resources {
-		a = append(a, App{r.Entity.Name, r.Metadata.Guid})
+	url := "/v3/apps?" + params.Encode()
+	for url != "" {
+		resp, err := c.c.Curl(url, "GET", "")
+		if err != nil {
+			return nil, err
+		}
+
+		var apps struct {
+			Pagination Pagination `json:"pagination"`
+			Resources  []struct {
+				Guid string `json:"guid"`
+				Name string `json:"name"`
+			} `json:"resources"`
+		}
+		err = json.Unmarshal(resp, &apps)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range apps.Resources {
+			a = append(a, App{r.Name, r.Guid})
+		}
+
+		url = apps.Pagination.Next.Path()
 	}
 
 	return a, nil
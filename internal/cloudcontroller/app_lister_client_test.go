@@ -2,6 +2,7 @@ package cloudcontroller_test
 
 import (
 	"errors"
+	"sync"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -20,50 +21,127 @@ var _ = Describe("AppListerClient", func() {
 		c = cloudcontroller.NewAppListerClient(curler)
 	})
 
-	It("requests all apps in the space", func() {
-		curler.resps["/v2/apps?q=space_guid:some-space"] = `
+	It("requests every page of apps in the space", func() {
+		curler.resps["/v3/apps?space_guids=some-space"] = `
 		{
+			"pagination": {
+				"next": {"href": "https://api.example.com/v3/apps?page=2&space_guids=some-space"}
+			},
 			"resources": [
-			{
-				"metadata":{"guid":"a"},
-				"entity": {"name":"app-1"}
+				{"guid": "a", "name": "app-1"},
+				{"guid": "b", "name": "app-2"}
+			]
+		}
+		`
+		curler.resps["/v3/apps?page=2&space_guids=some-space"] = `
+		{
+			"pagination": {
+				"next": null
 			},
-			{
-				"metadata":{"guid":"b"},
-				"entity": {"name":"app-2"}
-			}
+			"resources": [
+				{"guid": "c", "name": "app-3"}
 			]
 		}
 		`
+
 		apps, err := c.ListApps("some-space")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(curler.methods).To(ConsistOf("GET"))
-		Expect(curler.URLs).To(ConsistOf("/v2/apps?q=space_guid:some-space"))
-		Expect(apps).To(ConsistOf(
-			cloudcontroller.App{
-				Name: "app-1",
-				Guid: "a",
-			},
-			cloudcontroller.App{
-				Name: "app-2",
-				Guid: "b",
-			}))
+		Expect(curler.methods).To(ConsistOf("GET", "GET"))
+		Expect(curler.URLs).To(Equal([]string{
+			"/v3/apps?space_guids=some-space",
+			"/v3/apps?page=2&space_guids=some-space",
+		}))
+		Expect(apps).To(Equal([]cloudcontroller.App{
+			{Name: "app-1", Guid: "a"},
+			{Name: "app-2", Guid: "b"},
+			{Name: "app-3", Guid: "c"},
+		}))
 	})
 
 	It("returns an error if the GET fails", func() {
-		curler.errs["/v2/apps?q=space_guid:some-space"] = errors.New("some-error")
+		curler.errs["/v3/apps?space_guids=some-space"] = errors.New("some-error")
+		_, err := c.ListApps("some-space")
+		Expect(err).To(MatchError("some-error"))
+	})
+
+	It("returns an error if a later page fails", func() {
+		curler.resps["/v3/apps?space_guids=some-space"] = `
+		{
+			"pagination": {
+				"next": {"href": "https://api.example.com/v3/apps?page=2&space_guids=some-space"}
+			},
+			"resources": []
+		}
+		`
+		curler.errs["/v3/apps?page=2&space_guids=some-space"] = errors.New("some-error")
 		_, err := c.ListApps("some-space")
 		Expect(err).To(MatchError("some-error"))
 	})
 
 	It("returns an error if the JSON is invalid", func() {
-		curler.resps["/v2/apps?q=space_guid:some-space"] = `invalid`
+		curler.resps["/v3/apps?space_guids=some-space"] = `invalid`
 		_, err := c.ListApps("some-space")
 		Expect(err).To(HaveOccurred())
 	})
 })
 
+var _ = Describe("AppListerClient ListAppsByLabel", func() {
+	var (
+		curler *stubCurler
+		c      *cloudcontroller.AppListerClient
+	)
+
+	BeforeEach(func() {
+		curler = newStubCurler()
+		c = cloudcontroller.NewAppListerClient(curler)
+	})
+
+	It("requests every page of apps matching the label selector", func() {
+		curler.resps["/v3/apps?label_selector=team%3Dbilling&space_guids=some-space"] = `
+		{
+			"pagination": {
+				"next": {"href": "https://api.example.com/v3/apps?label_selector=team%3Dbilling&page=2&space_guids=some-space"}
+			},
+			"resources": [
+				{"guid": "a", "name": "app-1"}
+			]
+		}
+		`
+		curler.resps["/v3/apps?label_selector=team%3Dbilling&page=2&space_guids=some-space"] = `
+		{
+			"pagination": {
+				"next": null
+			},
+			"resources": [
+				{"guid": "b", "name": "app-2"}
+			]
+		}
+		`
+
+		apps, err := c.ListAppsByLabel("some-space", "team=billing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.methods).To(ConsistOf("GET", "GET"))
+		Expect(apps).To(Equal([]cloudcontroller.App{
+			{Name: "app-1", Guid: "a"},
+			{Name: "app-2", Guid: "b"},
+		}))
+	})
+
+	It("returns an error if the GET fails", func() {
+		curler.errs["/v3/apps?label_selector=team%3Dbilling&space_guids=some-space"] = errors.New("some-error")
+		_, err := c.ListAppsByLabel("some-space", "team=billing")
+		Expect(err).To(MatchError("some-error"))
+	})
+
+	It("returns an error if the JSON is invalid", func() {
+		curler.resps["/v3/apps?label_selector=team%3Dbilling&space_guids=some-space"] = `invalid`
+		_, err := c.ListAppsByLabel("some-space", "team=billing")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
 type stubCurler struct {
+	mu      sync.Mutex
 	URLs    []string
 	methods []string
 	bodies  []string
@@ -79,6 +157,9 @@ func newStubCurler() *stubCurler {
 }
 
 func (s *stubCurler) Curl(URL, method, body string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.URLs = append(s.URLs, URL)
 	s.methods = append(s.methods, method)
 	s.bodies = append(s.bodies, body)
@@ -0,0 +1,87 @@
+package cloudcontroller
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// AuditEvent is a Cloud Controller v3 audit event. The target is the
+// resource the event is about, e.g. the app that was created.
+type AuditEvent struct {
+	Guid       string
+	CreatedAt  time.Time
+	SpaceGuid  string
+	TargetGuid string
+	TargetName string
+}
+
+type AuditEventClient struct {
+	c Curler
+}
+
+func NewAuditEventClient(c Curler) *AuditEventClient {
+	return &AuditEventClient{
+		c: c,
+	}
+}
+
+// AppCreateEvents returns the audit.app.create events in the space that were
+// created at or after the given time, oldest first.
+func (c *AuditEventClient) AppCreateEvents(spaceGuid string, since time.Time) ([]AuditEvent, error) {
+	return c.appCreateEvents(url.Values{"space_guids": {spaceGuid}}, since)
+}
+
+// OrgAppCreateEvents returns the audit.app.create events in every space of
+// the org that were created at or after the given time, oldest first.
+func (c *AuditEventClient) OrgAppCreateEvents(orgGuid string, since time.Time) ([]AuditEvent, error) {
+	return c.appCreateEvents(url.Values{"organization_guids": {orgGuid}}, since)
+}
+
+func (c *AuditEventClient) appCreateEvents(params url.Values, since time.Time) ([]AuditEvent, error) {
+	params.Set("types", "audit.app.create")
+	params.Set("created_ats[gte]", since.UTC().Format(time.RFC3339))
+	params.Set("order_by", "created_at")
+
+	var events []AuditEvent
+	url := "/v3/audit_events?" + params.Encode()
+	for url != "" {
+		resp, err := c.c.Curl(url, "GET", "")
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Pagination Pagination `json:"pagination"`
+			Resources  []struct {
+				Guid      string    `json:"guid"`
+				CreatedAt time.Time `json:"created_at"`
+				Space     struct {
+					Guid string `json:"guid"`
+				} `json:"space"`
+				Target struct {
+					Guid string `json:"guid"`
+					Name string `json:"name"`
+				} `json:"target"`
+			} `json:"resources"`
+		}
+		err = json.Unmarshal(resp, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range page.Resources {
+			events = append(events, AuditEvent{
+				Guid:       r.Guid,
+				CreatedAt:  r.CreatedAt,
+				SpaceGuid:  r.Space.Guid,
+				TargetGuid: r.Target.Guid,
+				TargetName: r.Target.Name,
+			})
+		}
+
+		url = page.Pagination.Next.Path()
+	}
+
+	return events, nil
+}
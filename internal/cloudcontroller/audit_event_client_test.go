@@ -0,0 +1,125 @@
+package cloudcontroller_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+)
+
+var _ = Describe("AuditEventClient", func() {
+	var (
+		curler *stubCurler
+		c      *cloudcontroller.AuditEventClient
+		since  time.Time
+		url    string
+	)
+
+	BeforeEach(func() {
+		curler = newStubCurler()
+		c = cloudcontroller.NewAuditEventClient(curler)
+		since = time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+		url = "/v3/audit_events?created_ats%5Bgte%5D=2018-01-02T03%3A04%3A05Z&order_by=created_at&space_guids=some-space&types=audit.app.create"
+	})
+
+	It("requests every page of app create events in the space", func() {
+		curler.resps[url] = `
+		{
+			"pagination": {
+				"next": {"href": "https://api.example.com/v3/audit_events?page=2"}
+			},
+			"resources": [
+				{
+					"guid": "event-1",
+					"created_at": "2018-01-02T03:04:06Z",
+					"space": {"guid": "some-space"},
+					"target": {"guid": "app-guid-1", "name": "app-1", "type": "app"}
+				}
+			]
+		}
+		`
+		curler.resps["/v3/audit_events?page=2"] = `
+		{
+			"pagination": {
+				"next": null
+			},
+			"resources": [
+				{
+					"guid": "event-2",
+					"created_at": "2018-01-02T03:04:07Z",
+					"space": {"guid": "some-space"},
+					"target": {"guid": "app-guid-2", "name": "app-2", "type": "app"}
+				}
+			]
+		}
+		`
+
+		events, err := c.AppCreateEvents("some-space", since)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.URLs).To(Equal([]string{url, "/v3/audit_events?page=2"}))
+		Expect(curler.methods).To(ConsistOf("GET", "GET"))
+		Expect(events).To(Equal([]cloudcontroller.AuditEvent{
+			{
+				Guid:       "event-1",
+				CreatedAt:  time.Date(2018, 1, 2, 3, 4, 6, 0, time.UTC),
+				SpaceGuid:  "some-space",
+				TargetGuid: "app-guid-1",
+				TargetName: "app-1",
+			},
+			{
+				Guid:       "event-2",
+				CreatedAt:  time.Date(2018, 1, 2, 3, 4, 7, 0, time.UTC),
+				SpaceGuid:  "some-space",
+				TargetGuid: "app-guid-2",
+				TargetName: "app-2",
+			},
+		}))
+	})
+
+	It("requests the app create events in every space of the org", func() {
+		orgURL := "/v3/audit_events?created_ats%5Bgte%5D=2018-01-02T03%3A04%3A05Z&order_by=created_at&organization_guids=some-org&types=audit.app.create"
+		curler.resps[orgURL] = `
+		{
+			"pagination": {
+				"next": null
+			},
+			"resources": [
+				{
+					"guid": "event-1",
+					"created_at": "2018-01-02T03:04:06Z",
+					"space": {"guid": "other-space"},
+					"target": {"guid": "app-guid-1", "name": "app-1", "type": "app"}
+				}
+			]
+		}
+		`
+
+		events, err := c.OrgAppCreateEvents("some-org", since)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.URLs).To(Equal([]string{orgURL}))
+		Expect(events).To(Equal([]cloudcontroller.AuditEvent{
+			{
+				Guid:       "event-1",
+				CreatedAt:  time.Date(2018, 1, 2, 3, 4, 6, 0, time.UTC),
+				SpaceGuid:  "other-space",
+				TargetGuid: "app-guid-1",
+				TargetName: "app-1",
+			},
+		}))
+	})
+
+	It("returns an error if the GET fails", func() {
+		curler.errs[url] = errors.New("some-error")
+		_, err := c.AppCreateEvents("some-space", since)
+		Expect(err).To(MatchError("some-error"))
+	})
+
+	It("returns an error if the JSON is invalid", func() {
+		curler.resps[url] = `invalid`
+		_, err := c.AppCreateEvents("some-space", since)
+		Expect(err).To(HaveOccurred())
+	})
+})
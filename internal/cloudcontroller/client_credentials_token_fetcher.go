@@ -0,0 +1,70 @@
+package cloudcontroller
+
+import "sync"
+
+// ClientCredentialsUAAClient fetches access tokens with the UAA
+// client_credentials grant.
+type ClientCredentialsUAAClient interface {
+	GetAuthToken(clientID, clientSecret string, insecureSkipVerify bool) (string, error)
+}
+
+// ClientCredentialsTokenFetcher fetches access tokens for a UAA client
+// instead of a user. There is no refresh token, so nothing needs to be
+// saved when a token is fetched.
+type ClientCredentialsTokenFetcher struct {
+	uaa                ClientCredentialsUAAClient
+	clientID           string
+	clientSecret       string
+	insecureSkipVerify bool
+
+	mu    sync.Mutex
+	retry tokenRetrier
+}
+
+type ClientCredentialsTokenFetcherOption func(*ClientCredentialsTokenFetcher)
+
+// WithClientCredentialsTokenRetryPolicy sets the retry policy. By default a
+// failed fetch is not retried and the process exits.
+func WithClientCredentialsTokenRetryPolicy(p TokenRetryPolicy) ClientCredentialsTokenFetcherOption {
+	return func(f *ClientCredentialsTokenFetcher) {
+		f.retry.policy = p
+	}
+}
+
+func NewClientCredentialsTokenFetcher(
+	uaa ClientCredentialsUAAClient,
+	clientID string,
+	clientSecret string,
+	skipCertVerify bool,
+	log Logger,
+	opts ...ClientCredentialsTokenFetcherOption,
+) *ClientCredentialsTokenFetcher {
+	f := &ClientCredentialsTokenFetcher{
+		uaa:                uaa,
+		clientID:           clientID,
+		clientSecret:       clientSecret,
+		insecureSkipVerify: skipCertVerify,
+		retry: tokenRetrier{
+			log:    log,
+			policy: defaultTokenRetryPolicy,
+		},
+	}
+
+	for _, o := range opts {
+		o(f)
+	}
+
+	return f
+}
+
+// Token returns an access token and an empty refresh token. Errors are of
+// type *TokenError.
+func (f *ClientCredentialsTokenFetcher) Token() (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.retry.fetch(func() (string, string, error) {
+		accToken, err := f.uaa.GetAuthToken(f.clientID, f.clientSecret, f.insecureSkipVerify)
+		return accToken, "", err
+	})
+}
@@ -0,0 +1,133 @@
+package cloudcontroller_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+)
+
+var _ = Describe("ClientCredentialsTokenFetcher", func() {
+	var (
+		uaa    *spyClientCredentialsUAAClient
+		logger *stubLogger
+		f      *cloudcontroller.ClientCredentialsTokenFetcher
+	)
+
+	BeforeEach(func() {
+		uaa = &spyClientCredentialsUAAClient{
+			respAccessToken: "bearer access-token",
+		}
+		logger = newStubLogger()
+		f = cloudcontroller.NewClientCredentialsTokenFetcher(uaa, "client-id", "client-secret", true, logger,
+			cloudcontroller.WithClientCredentialsTokenRetryPolicy(cloudcontroller.TokenRetryPolicy{Attempts: 1}),
+		)
+	})
+
+	It("fetches an access token with the client credentials", func() {
+		token, refToken, err := f.Token()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(token).To(Equal("bearer access-token"))
+		Expect(refToken).To(BeEmpty())
+
+		Expect(uaa.reqClientID).To(Equal("client-id"))
+		Expect(uaa.reqClientSecret).To(Equal("client-secret"))
+		Expect(uaa.reqSkipCertVerify).To(BeTrue())
+	})
+
+	It("returns a classified error if UAA rejects the client", func() {
+		uaa.respError = errors.New("Received a status code 401 Unauthorized")
+
+		_, _, err := f.Token()
+		Expect(err).To(MatchError("failed to fetch tokens from UAA (rejected): Received a status code 401 Unauthorized"))
+	})
+
+	It("returns a classified error if UAA is unavailable", func() {
+		uaa.respError = errors.New("connection refused")
+
+		_, _, err := f.Token()
+		Expect(err.(*cloudcontroller.TokenError).Cause).To(Equal(cloudcontroller.TokenUnavailable))
+	})
+
+	It("retries if UAA is unavailable", func() {
+		uaa.respErrors = []error{errors.New("connection refused"), errors.New("connection refused")}
+		f = cloudcontroller.NewClientCredentialsTokenFetcher(uaa, "client-id", "client-secret", true, logger,
+			cloudcontroller.WithClientCredentialsTokenRetryPolicy(cloudcontroller.TokenRetryPolicy{
+				Attempts: 3,
+				Backoff:  time.Millisecond,
+			}),
+		)
+
+		token, _, err := f.Token()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token).To(Equal("bearer access-token"))
+		Expect(uaa.called).To(Equal(3))
+		Expect(logger.printfMessages).To(HaveLen(2))
+	})
+
+	It("does not retry if UAA rejects the client", func() {
+		uaa.respError = errors.New("Received a status code 401 Unauthorized")
+		f = cloudcontroller.NewClientCredentialsTokenFetcher(uaa, "client-id", "client-secret", true, logger,
+			cloudcontroller.WithClientCredentialsTokenRetryPolicy(cloudcontroller.TokenRetryPolicy{
+				Attempts: 3,
+				Backoff:  time.Millisecond,
+			}),
+		)
+
+		_, _, err := f.Token()
+		Expect(err).To(HaveOccurred())
+		Expect(uaa.called).To(Equal(1))
+	})
+
+	It("exits after too many consecutive failures", func() {
+		uaa.respError = errors.New("connection refused")
+		f = cloudcontroller.NewClientCredentialsTokenFetcher(uaa, "client-id", "client-secret", true, logger,
+			cloudcontroller.WithClientCredentialsTokenRetryPolicy(cloudcontroller.TokenRetryPolicy{
+				Attempts:               1,
+				MaxConsecutiveFailures: 2,
+			}),
+		)
+
+		_, _, err := f.Token()
+		Expect(err).To(HaveOccurred())
+		Expect(func() { f.Token() }).To(Panic())
+		Expect(logger.called).To(Equal(1))
+	})
+
+	It("exits on the first failure by default", func() {
+		uaa.respError = errors.New("connection refused")
+		f = cloudcontroller.NewClientCredentialsTokenFetcher(uaa, "client-id", "client-secret", true, logger)
+
+		Expect(func() { f.Token() }).To(Panic())
+	})
+})
+
+type spyClientCredentialsUAAClient struct {
+	reqClientID       string
+	reqClientSecret   string
+	reqSkipCertVerify bool
+
+	called          int
+	respAccessToken string
+	respError       error
+	respErrors      []error
+}
+
+func (s *spyClientCredentialsUAAClient) GetAuthToken(clientID, clientSecret string, insecureSkipVerify bool) (string, error) {
+	s.reqClientID = clientID
+	s.reqClientSecret = clientSecret
+	s.reqSkipCertVerify = insecureSkipVerify
+	s.called++
+
+	if len(s.respErrors) > 0 {
+		err := s.respErrors[0]
+		s.respErrors = s.respErrors[1:]
+		return "", err
+	}
+
+	return s.respAccessToken, s.respError
+}
@@ -1,42 +1,107 @@
 package cloudcontroller
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/drainurl"
 )
 
 type CreateDrainClient struct {
-	c Curler
+	c              Curler
+	skipValidation bool
+	tags           []string
 }
 
-func NewCreateDrainClient(c Curler) *CreateDrainClient {
-	return &CreateDrainClient{
+func NewCreateDrainClient(c Curler, opts ...CreateDrainClientOption) *CreateDrainClient {
+	client := &CreateDrainClient{
 		c: c,
 	}
+
+	for _, o := range opts {
+		o(client)
+	}
+
+	return client
 }
 
-func (c *CreateDrainClient) CreateDrain(name, url, spaceGuid, drainType string) error {
-	if !validDrainType(drainType) {
-		return fmt.Errorf("invalid drain type: %s", drainType)
+type CreateDrainClientOption func(c *CreateDrainClient)
+
+// WithCreateDrainSkipValidation disables the validation of drain URLs
+// before the drain is created.
+func WithCreateDrainSkipValidation(skip bool) CreateDrainClientOption {
+	return func(c *CreateDrainClient) {
+		c.skipValidation = skip
+	}
+}
+
+// WithCreateDrainTags sets the tags of the created drains. Tags can be
+// used to find the drains created by a client later on.
+func WithCreateDrainTags(tags ...string) CreateDrainClientOption {
+	return func(c *CreateDrainClient) {
+		c.tags = tags
 	}
+}
 
-	url = fmt.Sprintf("%s?drain-type=%s", url, drainType)
+func (c *CreateDrainClient) CreateDrain(name, drainURL, spaceGuid, drainType string) error {
+	u, err := DrainURLWithType(drainURL, drainType)
+	if err != nil {
+		return err
+	}
 
-	_, err := c.c.Curl(
+	if !c.skipValidation {
+		if err := drainurl.Validate(u); err != nil {
+			return fmt.Errorf("invalid drain URL: %s", err)
+		}
+	}
+
+	_, err = c.c.Curl(
 		"/v2/user_provided_service_instances",
 		"POST",
-		c.buildRequestBody(name, url, spaceGuid),
+		c.buildRequestBody(name, u, spaceGuid),
 	)
 
 	return err
 }
 
+// DrainURLWithType returns the drain URL with its drain-type set. This is
+// the URL CreateDrain creates the drain with.
+func DrainURLWithType(drainURL, drainType string) (string, error) {
+	if !validDrainType(drainType) {
+		return "", fmt.Errorf("invalid drain type: %s", drainType)
+	}
+
+	u, err := url.Parse(drainURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid drain URL: %s", err)
+	}
+
+	query := u.Query()
+	query.Set("drain-type", drainType)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
 func (c *CreateDrainClient) buildRequestBody(name, url, spaceGuid string) string {
-	return fmt.Sprintf(`
+	if len(c.tags) == 0 {
+		return fmt.Sprintf(`
 	{
 	  "syslog_drain_url": %q,
 	  "space_guid": %q,
 	  "name": %q
 	}`, url, spaceGuid, name)
+	}
+
+	tags, _ := json.Marshal(c.tags)
+	return fmt.Sprintf(`
+	{
+	  "syslog_drain_url": %q,
+	  "space_guid": %q,
+	  "name": %q,
+	  "tags": %s
+	}`, url, spaceGuid, name, tags)
 }
 
 func validDrainType(drainType string) bool {
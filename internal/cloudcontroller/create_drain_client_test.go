@@ -24,7 +24,7 @@ var _ = Describe("CreateDrainClient", func() {
 	})
 
 	It("POSTs the request to the Curler", func() {
-		err := c.CreateDrain("some-name", "some-url", "some-space", "all")
+		err := c.CreateDrain("some-name", "syslog://some-url:514", "some-space", "all")
 		Expect(err).ToNot(HaveOccurred())
 		Expect(curler.methods).To(ConsistOf("POST"))
 		Expect(curler.URLs).To(ConsistOf("/v2/user_provided_service_instances"))
@@ -32,26 +32,44 @@ var _ = Describe("CreateDrainClient", func() {
 		{
 		   "space_guid": "some-space",
 		   "name": "some-name",
-		   "syslog_drain_url": "some-url?drain-type=all"
+		   "syslog_drain_url": "syslog://some-url:514?drain-type=all"
+		}`,
+		)))
+	})
+
+	It("sets the tags of the drain", func() {
+		c = cloudcontroller.NewCreateDrainClient(
+			curler,
+			cloudcontroller.WithCreateDrainTags("space-drain:some-app-guid"),
+		)
+
+		err := c.CreateDrain("some-name", "syslog://some-url:514", "some-space", "all")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.bodies).To(ConsistOf(MatchJSON(`
+		{
+		   "space_guid": "some-space",
+		   "name": "some-name",
+		   "syslog_drain_url": "syslog://some-url:514?drain-type=all",
+		   "tags": ["space-drain:some-app-guid"]
 		}`,
 		)))
 	})
 
 	It("returns an error if the POST fails", func() {
 		curler.errs["/v2/user_provided_service_instances"] = errors.New("some-error")
-		err := c.CreateDrain("some-name", "some-url", "some-space", "all")
+		err := c.CreateDrain("some-name", "syslog://some-url:514", "some-space", "all")
 		Expect(err).To(MatchError("some-error"))
 	})
 
 	DescribeTable("drain types", func(drainType string) {
-		err := c.CreateDrain("some-name", "some-url", "some-space", drainType)
+		err := c.CreateDrain("some-name", "syslog://some-url:514", "some-space", drainType)
 
 		Expect(err).ToNot(HaveOccurred())
 		Expect(curler.bodies).To(ConsistOf(MatchJSON(fmt.Sprintf(`
 			{
 			   "space_guid": "some-space",
 			   "name": "some-name",
-			   "syslog_drain_url": "some-url?drain-type=%s"
+			   "syslog_drain_url": "syslog://some-url:514?drain-type=%s"
 			}`, drainType),
 		)))
 	},
@@ -60,9 +78,44 @@ var _ = Describe("CreateDrainClient", func() {
 		Entry("drain-type=logs", "logs"),
 	)
 
+	It("keeps existing query parameters", func() {
+		err := c.CreateDrain("some-name", "syslog://some-url:514?omit-metadata=true", "some-space", "logs")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.bodies).To(ConsistOf(MatchJSON(`
+		{
+		   "space_guid": "some-space",
+		   "name": "some-name",
+		   "syslog_drain_url": "syslog://some-url:514?drain-type=logs&omit-metadata=true"
+		}`,
+		)))
+	})
+
+	It("returns an error for invalid drain URLs", func() {
+		err := c.CreateDrain("some-name", "sylog://some-url:514", "some-space", "all")
+		Expect(err).To(MatchError(`invalid drain URL: unsupported scheme "sylog", expected one of syslog, syslog-tls, https`))
+		Expect(curler.URLs).To(BeEmpty())
+	})
+
+	It("skips validation when configured to", func() {
+		c = cloudcontroller.NewCreateDrainClient(
+			curler,
+			cloudcontroller.WithCreateDrainSkipValidation(true),
+		)
+
+		err := c.CreateDrain("some-name", "sylog://some-url", "some-space", "all")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.bodies).To(ConsistOf(MatchJSON(`
+		{
+		   "space_guid": "some-space",
+		   "name": "some-name",
+		   "syslog_drain_url": "sylog://some-url?drain-type=all"
+		}`,
+		)))
+	})
+
 	It("fatally logs for unknown drain types", func() {
 		invalidType := fmt.Sprintf("invalid-%d", time.Now().UnixNano())
-		err := c.CreateDrain("some-name", "some-url", "some-space", invalidType)
+		err := c.CreateDrain("some-name", "syslog://some-url:514", "some-space", invalidType)
 		Expect(err).To(MatchError(fmt.Sprintf("invalid drain type: %s", invalidType)))
 		Expect(curler.URLs).To(BeEmpty())
 	})
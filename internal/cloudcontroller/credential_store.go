@@ -0,0 +1,231 @@
+package cloudcontroller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// RefreshTokenCredential is the key of the refresh token in the credentials
+// of a service instance.
+const RefreshTokenCredential = "refresh_token"
+
+// ClientSecretCredential is the key of the secret of the UAA client in the
+// credentials of a service instance.
+const ClientSecretCredential = "client_secret"
+
+// CredentialStore stores the refresh token or the client secret of the
+// drain app.
+type CredentialStore interface {
+	// ClientSecret returns the secret of the UAA client the app
+	// authenticates as. It is empty if the app uses a refresh token.
+	ClientSecret() string
+
+	// RefreshToken returns the refresh token the app was started with.
+	RefreshToken() (string, error)
+
+	// SaveRefreshToken stores a new refresh token.
+	SaveRefreshToken(refreshToken string) error
+
+	// RequiresRestage reports whether the app has to be restaged to be
+	// started with a saved refresh token.
+	RequiresRestage() bool
+}
+
+// EnvCredentialStore stores the refresh token in the REFRESH_TOKEN env var
+// of the app and reads the client secret from CLIENT_SECRET. Anybody who can
+// read the environment of the app can read them.
+type EnvCredentialStore struct {
+	c       AuthCurler
+	appGUID string
+}
+
+func NewEnvCredentialStore(appGUID string, c AuthCurler) *EnvCredentialStore {
+	return &EnvCredentialStore{
+		c:       c,
+		appGUID: appGUID,
+	}
+}
+
+func (s *EnvCredentialStore) ClientSecret() string {
+	return os.Getenv("CLIENT_SECRET")
+}
+
+func (s *EnvCredentialStore) RefreshToken() (string, error) {
+	refreshToken := os.Getenv("REFRESH_TOKEN")
+	if refreshToken == "" {
+		return "", errors.New("REFRESH_TOKEN is not set")
+	}
+
+	return refreshToken, nil
+}
+
+func (s *EnvCredentialStore) SaveRefreshToken(refreshToken string) error {
+	url := fmt.Sprintf("/v3/apps/%s/environment_variables", s.appGUID)
+	body := fmt.Sprintf(`{"var":{"REFRESH_TOKEN": %q}}`, refreshToken)
+	_, err := s.c.Curl(url, http.MethodPatch, body)
+	if err != nil {
+		return fmt.Errorf("failed to update REFRESH_TOKEN with cloud controller: %s", err)
+	}
+
+	return nil
+}
+
+// RequiresRestage is true as env vars are only updated on restage.
+func (s *EnvCredentialStore) RequiresRestage() bool {
+	return true
+}
+
+// BoundService is a service instance bound to the app as found in
+// VCAP_SERVICES.
+type BoundService struct {
+	Name         string                 `json:"name"`
+	InstanceGUID string                 `json:"instance_guid"`
+	Credentials  map[string]interface{} `json:"credentials"`
+}
+
+// FindBoundService returns the service instance with the given name from
+// the VCAP_SERVICES env var.
+func FindBoundService(vcapServices, name string) (BoundService, error) {
+	var services map[string][]BoundService
+	if err := json.Unmarshal([]byte(vcapServices), &services); err != nil {
+		return BoundService{}, fmt.Errorf("failed to parse VCAP_SERVICES: %s", err)
+	}
+
+	for _, instances := range services {
+		for _, s := range instances {
+			if s.Name == name {
+				return s, nil
+			}
+		}
+	}
+
+	return BoundService{}, fmt.Errorf("service %s is not bound to the app", name)
+}
+
+func (s BoundService) clientSecret() string {
+	clientSecret, _ := s.Credentials[ClientSecretCredential].(string)
+	return clientSecret
+}
+
+func (s BoundService) refreshToken() (string, error) {
+	refreshToken, _ := s.Credentials[RefreshTokenCredential].(string)
+	if refreshToken == "" {
+		return "", fmt.Errorf("service %s has no %s credential", s.Name, RefreshTokenCredential)
+	}
+
+	return refreshToken, nil
+}
+
+// UserProvidedServiceCredentialStore stores the refresh token in the
+// credentials of a user-provided service instance bound to the app. Saving
+// a refresh token updates the service instance in place, but VCAP_SERVICES
+// only holds the new credentials once the app is restaged. Without a
+// restage an app that restarts would use a refresh token UAA revoked.
+type UserProvidedServiceCredentialStore struct {
+	c       AuthCurler
+	service BoundService
+}
+
+func NewUserProvidedServiceCredentialStore(service BoundService, c AuthCurler) *UserProvidedServiceCredentialStore {
+	return &UserProvidedServiceCredentialStore{
+		c:       c,
+		service: service,
+	}
+}
+
+func (s *UserProvidedServiceCredentialStore) ClientSecret() string {
+	return s.service.clientSecret()
+}
+
+func (s *UserProvidedServiceCredentialStore) RefreshToken() (string, error) {
+	return s.service.refreshToken()
+}
+
+// SaveRefreshToken updates the credentials of the service instance. The
+// other credentials, e.g. the client secret, are kept as Cloud Controller
+// replaces all credentials.
+func (s *UserProvidedServiceCredentialStore) SaveRefreshToken(refreshToken string) error {
+	url := fmt.Sprintf("/v3/service_instances/%s", s.service.InstanceGUID)
+	body, err := credentialsBody("credentials", s.service.Credentials, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.c.Curl(url, http.MethodPatch, body)
+	if err != nil {
+		return fmt.Errorf("failed to update service %s with cloud controller: %s", s.service.Name, err)
+	}
+
+	return nil
+}
+
+// RequiresRestage is true as VCAP_SERVICES is only updated on restage.
+func (s *UserProvidedServiceCredentialStore) RequiresRestage() bool {
+	return true
+}
+
+// CredHubServiceCredentialStore stores the refresh token in a service
+// instance of a CredHub service broker bound to the app. The credentials
+// live in CredHub and are interpolated into VCAP_SERVICES every time an
+// instance starts, so the app is never restaged.
+type CredHubServiceCredentialStore struct {
+	c       AuthCurler
+	service BoundService
+}
+
+func NewCredHubServiceCredentialStore(service BoundService, c AuthCurler) *CredHubServiceCredentialStore {
+	return &CredHubServiceCredentialStore{
+		c:       c,
+		service: service,
+	}
+}
+
+func (s *CredHubServiceCredentialStore) ClientSecret() string {
+	return s.service.clientSecret()
+}
+
+func (s *CredHubServiceCredentialStore) RefreshToken() (string, error) {
+	return s.service.refreshToken()
+}
+
+// SaveRefreshToken updates the service instance with the refresh token as
+// parameter along with the other credentials. The broker replaces the
+// credentials in CredHub with them.
+func (s *CredHubServiceCredentialStore) SaveRefreshToken(refreshToken string) error {
+	url := fmt.Sprintf("/v3/service_instances/%s", s.service.InstanceGUID)
+	body, err := credentialsBody("parameters", s.service.Credentials, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.c.Curl(url, http.MethodPatch, body)
+	if err != nil {
+		return fmt.Errorf("failed to update service %s with cloud controller: %s", s.service.Name, err)
+	}
+
+	return nil
+}
+
+func (s *CredHubServiceCredentialStore) RequiresRestage() bool {
+	return false
+}
+
+func credentialsBody(field string, credentials map[string]interface{}, refreshToken string) (string, error) {
+	merged := make(map[string]interface{}, len(credentials)+1)
+	for k, v := range credentials {
+		merged[k] = v
+	}
+	merged[RefreshTokenCredential] = refreshToken
+
+	body, err := json.Marshal(map[string]map[string]interface{}{
+		field: merged,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,202 @@
+package cloudcontroller_test
+
+import (
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+)
+
+var _ = Describe("CredentialStore", func() {
+	var (
+		ac      *spyAuthCurler
+		service cloudcontroller.BoundService
+	)
+
+	BeforeEach(func() {
+		ac = &spyAuthCurler{}
+		service = cloudcontroller.BoundService{
+			Name:         "drain-credentials",
+			InstanceGUID: "service-guid",
+			Credentials: map[string]interface{}{
+				"refresh_token": "some-refresh-token",
+			},
+		}
+	})
+
+	Describe("EnvCredentialStore", func() {
+		var s *cloudcontroller.EnvCredentialStore
+
+		BeforeEach(func() {
+			s = cloudcontroller.NewEnvCredentialStore("app-guid", ac)
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("REFRESH_TOKEN")
+			os.Unsetenv("CLIENT_SECRET")
+		})
+
+		It("reads the client secret from CLIENT_SECRET", func() {
+			Expect(s.ClientSecret()).To(BeEmpty())
+
+			os.Setenv("CLIENT_SECRET", "some-secret")
+			Expect(s.ClientSecret()).To(Equal("some-secret"))
+		})
+
+		It("reads the refresh token from REFRESH_TOKEN", func() {
+			os.Setenv("REFRESH_TOKEN", "some-refresh-token")
+
+			refreshToken, err := s.RefreshToken()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refreshToken).To(Equal("some-refresh-token"))
+		})
+
+		It("returns an error if REFRESH_TOKEN is not set", func() {
+			_, err := s.RefreshToken()
+			Expect(err).To(MatchError("REFRESH_TOKEN is not set"))
+		})
+
+		It("saves the refresh token in the env vars of the app", func() {
+			err := s.SaveRefreshToken("new-refresh-token")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ac.urls).To(Equal([]string{"/v3/apps/app-guid/environment_variables"}))
+			Expect(ac.methods).To(Equal([]string{"PATCH"}))
+			Expect(ac.bodies[0]).To(MatchJSON(`{"var": {"REFRESH_TOKEN": "new-refresh-token"}}`))
+		})
+
+		It("requires a restage", func() {
+			Expect(s.RequiresRestage()).To(BeTrue())
+		})
+	})
+
+	Describe("UserProvidedServiceCredentialStore", func() {
+		var s *cloudcontroller.UserProvidedServiceCredentialStore
+
+		BeforeEach(func() {
+			s = cloudcontroller.NewUserProvidedServiceCredentialStore(service, ac)
+		})
+
+		It("reads the refresh token from the service credentials", func() {
+			refreshToken, err := s.RefreshToken()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refreshToken).To(Equal("some-refresh-token"))
+		})
+
+		It("reads the client secret from the service credentials", func() {
+			Expect(s.ClientSecret()).To(BeEmpty())
+
+			service.Credentials = map[string]interface{}{"client_secret": "some-secret"}
+			s = cloudcontroller.NewUserProvidedServiceCredentialStore(service, ac)
+			Expect(s.ClientSecret()).To(Equal("some-secret"))
+		})
+
+		It("returns an error if the service has no refresh token", func() {
+			service.Credentials = nil
+			s = cloudcontroller.NewUserProvidedServiceCredentialStore(service, ac)
+
+			_, err := s.RefreshToken()
+			Expect(err).To(MatchError("service drain-credentials has no refresh_token credential"))
+		})
+
+		It("saves the refresh token in the service credentials", func() {
+			err := s.SaveRefreshToken("new-refresh-token")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ac.urls).To(Equal([]string{"/v3/service_instances/service-guid"}))
+			Expect(ac.methods).To(Equal([]string{"PATCH"}))
+			Expect(ac.bodies[0]).To(MatchJSON(`{"credentials": {"refresh_token": "new-refresh-token"}}`))
+		})
+
+		It("keeps the other service credentials", func() {
+			service.Credentials["client_secret"] = "some-secret"
+			s = cloudcontroller.NewUserProvidedServiceCredentialStore(service, ac)
+
+			err := s.SaveRefreshToken("new-refresh-token")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ac.bodies[0]).To(MatchJSON(`{"credentials": {"refresh_token": "new-refresh-token", "client_secret": "some-secret"}}`))
+			Expect(service.Credentials["refresh_token"]).To(Equal("some-refresh-token"))
+		})
+
+		It("returns an error if the service can not be updated", func() {
+			ac.errs = []error{errors.New("CAPI is down")}
+
+			err := s.SaveRefreshToken("new-refresh-token")
+			Expect(err).To(MatchError("failed to update service drain-credentials with cloud controller: CAPI is down"))
+		})
+
+		It("requires a restage", func() {
+			Expect(s.RequiresRestage()).To(BeTrue())
+		})
+	})
+
+	Describe("CredHubServiceCredentialStore", func() {
+		var s *cloudcontroller.CredHubServiceCredentialStore
+
+		BeforeEach(func() {
+			s = cloudcontroller.NewCredHubServiceCredentialStore(service, ac)
+		})
+
+		It("reads the refresh token from the interpolated credentials", func() {
+			refreshToken, err := s.RefreshToken()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refreshToken).To(Equal("some-refresh-token"))
+		})
+
+		It("reads the client secret from the interpolated credentials", func() {
+			service.Credentials = map[string]interface{}{"client_secret": "some-secret"}
+			s = cloudcontroller.NewCredHubServiceCredentialStore(service, ac)
+			Expect(s.ClientSecret()).To(Equal("some-secret"))
+		})
+
+		It("saves the refresh token as service parameter", func() {
+			err := s.SaveRefreshToken("new-refresh-token")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ac.urls).To(Equal([]string{"/v3/service_instances/service-guid"}))
+			Expect(ac.methods).To(Equal([]string{"PATCH"}))
+			Expect(ac.bodies[0]).To(MatchJSON(`{"parameters": {"refresh_token": "new-refresh-token"}}`))
+		})
+
+		It("keeps the other credentials", func() {
+			service.Credentials["client_secret"] = "some-secret"
+			s = cloudcontroller.NewCredHubServiceCredentialStore(service, ac)
+
+			err := s.SaveRefreshToken("new-refresh-token")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ac.bodies[0]).To(MatchJSON(`{"parameters": {"refresh_token": "new-refresh-token", "client_secret": "some-secret"}}`))
+		})
+
+		It("does not require a restage", func() {
+			Expect(s.RequiresRestage()).To(BeFalse())
+		})
+	})
+
+	Describe("FindBoundService", func() {
+		It("finds the service by name", func() {
+			s, err := cloudcontroller.FindBoundService(`{
+				"user-provided": [
+					{"name": "other", "instance_guid": "other-guid", "credentials": {}},
+					{"name": "drain-credentials", "instance_guid": "service-guid", "credentials": {"refresh_token": "some-refresh-token"}}
+				]
+			}`, "drain-credentials")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal(service))
+		})
+
+		It("returns an error if the service is not bound", func() {
+			_, err := cloudcontroller.FindBoundService(`{"credhub": []}`, "drain-credentials")
+			Expect(err).To(MatchError("service drain-credentials is not bound to the app"))
+		})
+
+		It("returns an error for invalid VCAP_SERVICES", func() {
+			_, err := cloudcontroller.FindBoundService("", "drain-credentials")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
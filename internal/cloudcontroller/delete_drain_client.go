@@ -0,0 +1,18 @@
+package cloudcontroller
+
+type DeleteDrainClient struct {
+	c Curler
+}
+
+func NewDeleteDrainClient(c Curler) *DeleteDrainClient {
+	return &DeleteDrainClient{
+		c: c,
+	}
+}
+
+// DeleteDrain deletes the drain's user provided service instance. Apps have
+// to be unbound from the drain first.
+func (c *DeleteDrainClient) DeleteDrain(serviceInstanceGuid string) error {
+	_, err := c.c.Curl("/v3/service_instances/"+serviceInstanceGuid, "DELETE", "")
+	return err
+}
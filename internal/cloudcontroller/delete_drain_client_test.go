@@ -0,0 +1,36 @@
+package cloudcontroller_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+)
+
+var _ = Describe("DeleteDrainClient", func() {
+	var (
+		curler *stubCurler
+		c      *cloudcontroller.DeleteDrainClient
+	)
+
+	BeforeEach(func() {
+		curler = newStubCurler()
+		c = cloudcontroller.NewDeleteDrainClient(curler)
+	})
+
+	It("DELETEs the service instance", func() {
+		err := c.DeleteDrain("drain-guid")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.URLs).To(ConsistOf("/v3/service_instances/drain-guid"))
+		Expect(curler.methods).To(ConsistOf("DELETE"))
+	})
+
+	It("returns an error if the DELETE fails", func() {
+		curler.errs["/v3/service_instances/drain-guid"] = errors.New("some-error")
+
+		err := c.DeleteDrain("drain-guid")
+		Expect(err).To(MatchError("some-error"))
+	})
+})
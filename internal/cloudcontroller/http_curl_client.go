@@ -1,6 +1,8 @@
 package cloudcontroller
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +11,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 )
 
 type HTTPCurlClient struct {
@@ -17,10 +20,27 @@ type HTTPCurlClient struct {
 	r SaveAndRestager
 	a string
 
-	mu          sync.RWMutex
+	mu           sync.Mutex
+	accessToken  string
+	expiresAt    time.Time
+	refreshToken string
+	refresh      *tokenRefresh
+	// saving is the refresh token that is being saved.
+	saving string
+}
+
+// tokenRefresh is a token fetch that concurrent callers wait for instead of
+// fetching tokens themselves.
+type tokenRefresh struct {
+	done        chan struct{}
 	accessToken string
+	err         error
 }
 
+// tokenExpiryMargin is how long before it expires an access token is
+// refreshed.
+const tokenExpiryMargin = 30 * time.Second
+
 type Doer interface {
 	Do(*http.Request) (*http.Response, error)
 }
@@ -30,28 +50,63 @@ type TokenFetcher interface {
 }
 
 type SaveAndRestager interface {
-	SaveAndRestage(refreshToken string)
+	SaveAndRestage(refreshToken string) error
+}
+
+type SaveAndRestagerFunc func(string) error
+
+func (f SaveAndRestagerFunc) SaveAndRestage(refToken string) error {
+	return f(refToken)
 }
 
-type SaveAndRestagerFunc func(string)
+func NewHTTPCurlClient(apiAddr string, d Doer, f TokenFetcher, r SaveAndRestager, opts ...HTTPCurlClientOption) *HTTPCurlClient {
+	c := &HTTPCurlClient{d: d, f: f, a: apiAddr, r: r}
+
+	for _, o := range opts {
+		o(c)
+	}
 
-func (f SaveAndRestagerFunc) SaveAndRestage(refToken string) {
-	f(refToken)
+	return c
 }
 
-func NewHTTPCurlClient(apiAddr string, d Doer, f TokenFetcher, r SaveAndRestager) *HTTPCurlClient {
-	return &HTTPCurlClient{d: d, f: f, a: apiAddr, r: r}
+type HTTPCurlClientOption func(c *HTTPCurlClient)
+
+// WithHTTPCurlClientRefreshToken sets the refresh token the TokenFetcher
+// starts with. Refresh tokens fetched from UAA are only saved if they
+// differ from it.
+func WithHTTPCurlClientRefreshToken(refreshToken string) HTTPCurlClientOption {
+	return func(c *HTTPCurlClient) {
+		c.refreshToken = refreshToken
+	}
 }
 
+// Curl sends the request to Cloud Controller. If Cloud Controller responds
+// with a 401 the token is refreshed and the request is sent once more.
 func (c *HTTPCurlClient) Curl(url, method, body string) ([]byte, error) {
-	accToken, _, err := c.token()
+	accToken, err := c.token("")
 	if err != nil {
 		return nil, err
 	}
 
-	return c.authCurl(url, method, body, accToken)
+	data, err := c.authCurl(url, method, body, accToken)
+	if err != errUnauthorized {
+		return data, err
+	}
+
+	accToken, err = c.token(accToken)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected status code 401, %s", err)
+	}
+
+	data, err = c.authCurl(url, method, body, accToken)
+	if err == errUnauthorized {
+		return nil, errors.New("unexpected status code 401")
+	}
+	return data, err
 }
 
+var errUnauthorized = errors.New("unauthorized")
+
 func (c *HTTPCurlClient) authCurl(URL, method, body, token string) ([]byte, error) {
 	if method == http.MethodGet && body != "" {
 		log.Panic("GET method must not have a body")
@@ -84,14 +139,7 @@ func (c *HTTPCurlClient) authCurl(URL, method, body, token string) ([]byte, erro
 	}
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		c.accessToken = ""
-		var refToken string
-		_, refToken, err = c.token()
-		if err != nil {
-			return nil, err
-		}
-		c.r.SaveAndRestage(refToken)
-		return nil, errors.New("unexpected status code 401")
+		return nil, errUnauthorized
 	}
 
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
@@ -101,28 +149,127 @@ func (c *HTTPCurlClient) authCurl(URL, method, body, token string) ([]byte, erro
 	return data, nil
 }
 
-func (c *HTTPCurlClient) token() (string, string, error) {
-	c.mu.RLock()
-	accToken := c.accessToken
-	c.mu.RUnlock()
+// token returns the cached access token unless it is about to expire or
+// equals stale, e.g. because it got a 401. Otherwise the token is refreshed.
+// Only one refresh is in flight at a time, concurrent callers wait for it.
+func (c *HTTPCurlClient) token(stale string) (string, error) {
+	c.mu.Lock()
+	// A new token is handed out while its refresh token is saved, even if
+	// it expires soon, as saving it needs a token itself.
+	if c.accessToken != "" && c.accessToken != stale && (!c.expiresSoon() || c.saving != "") {
+		accToken := c.accessToken
+		c.mu.Unlock()
+		return accToken, nil
+	}
 
-	if accToken != "" {
-		return accToken, "", nil
+	r := c.refresh
+	if r != nil {
+		c.mu.Unlock()
+		<-r.done
+		return r.accessToken, r.err
 	}
 
-	// We are unprotected via locks here, which can imply that multiple
-	// go-routines are attempting to refresh the token at the same time. Both
-	// will succeed and both will save the token. This is a performance
-	// concern and unlikely. We are going to leave it for now.
+	r = &tokenRefresh{done: make(chan struct{})}
+	c.refresh = r
+	// Callers must not use the old token while it is refreshed.
+	c.accessToken = ""
+	c.mu.Unlock()
+
+	var refToken string
+	r.accessToken, refToken, r.err = c.fetchToken()
+
+	// The refresh is done before the refresh token is saved. Saving it
+	// sends requests that may get a 401 and refresh the token again.
+	c.mu.Lock()
+	c.refresh = nil
+	c.mu.Unlock()
+	close(r.done)
+
+	if r.err != nil || refToken == "" {
+		return r.accessToken, r.err
+	}
+
+	if err := c.saveRefreshToken(refToken); err != nil {
+		return "", err
+	}
+
+	// Saving may have refreshed the token again.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken != "" {
+		return c.accessToken, nil
+	}
+	return r.accessToken, nil
+}
 
-	accessToken, refToken, err := c.f.Token()
+// fetchToken fetches new tokens. It returns the refresh token if it has to
+// be saved because it differs from the saved one.
+func (c *HTTPCurlClient) fetchToken() (string, string, error) {
+	accToken, refToken, err := c.f.Token()
 	if err != nil {
 		return "", "", err
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.accessToken = accessToken
 
-	return c.accessToken, refToken, nil
+	c.accessToken = accToken
+	c.expiresAt = tokenExpiry(accToken)
+
+	// Client credentials have no refresh token to save.
+	if refToken == "" || refToken == c.refreshToken || refToken == c.saving {
+		return accToken, "", nil
+	}
+	c.saving = refToken
+
+	return accToken, refToken, nil
+}
+
+func (c *HTTPCurlClient) saveRefreshToken(refToken string) error {
+	err := c.r.SaveAndRestage(refToken)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.saving == refToken {
+		c.saving = ""
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save refresh token: %s", err)
+	}
+	c.refreshToken = refToken
+
+	return nil
+}
+
+func (c *HTTPCurlClient) expiresSoon() bool {
+	return !c.expiresAt.IsZero() && time.Now().Add(tokenExpiryMargin).After(c.expiresAt)
+}
+
+// tokenExpiry returns the time of the exp claim of a JWT access token. It
+// is zero if the token is not a JWT, such tokens are used until they get a
+// 401.
+func tokenExpiry(accToken string) time.Time {
+	if i := strings.LastIndex(accToken, " "); i >= 0 {
+		accToken = accToken[i+1:]
+	}
+
+	parts := strings.Split(accToken, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.Exp, 0)
 }
@@ -1,12 +1,15 @@
 package cloudcontroller_test
 
 import (
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -26,7 +29,13 @@ var _ = Describe("HttpCurlClient", func() {
 		doer = newSpyDoer()
 		fetcher = newSpyTokenFetcher()
 		restager = newSpySaveAndRestager()
-		c = cloudcontroller.NewHTTPCurlClient("https://api.system-domain.com", doer, fetcher, restager)
+		c = cloudcontroller.NewHTTPCurlClient(
+			"https://api.system-domain.com",
+			doer,
+			fetcher,
+			restager,
+			cloudcontroller.WithHTTPCurlClientRefreshToken("some-ref-token"),
+		)
 	})
 
 	It("hits the correct URL", func() {
@@ -73,6 +82,171 @@ var _ = Describe("HttpCurlClient", func() {
 		Expect(restager.refreshToken).To(Equal("some-other-ref-token"))
 	})
 
+	It("returns an error if saving the new refresh token fails after a 401", func() {
+		fetcher.tokens = []string{"some-token", "some-other-token"}
+		fetcher.refTokens = []string{"some-ref-token", "some-other-ref-token"}
+		fetcher.errs = []error{nil, nil}
+		doer.statusCode = http.StatusUnauthorized
+		restager.err = errors.New("some-error")
+
+		_, err := c.Curl("some-url", "PUT", "some-body")
+		Expect(err).To(MatchError("unexpected status code 401, failed to save refresh token: some-error"))
+	})
+
+	It("does not save an empty refresh token after a 401", func() {
+		fetcher.tokens = []string{"some-token", "some-other-token"}
+		fetcher.refTokens = []string{"", ""}
+		fetcher.errs = []error{nil, nil}
+		doer.statusCode = http.StatusUnauthorized
+		restager.refreshToken = "unchanged"
+
+		_, err := c.Curl("some-url", "PUT", "some-body")
+		Expect(err).To(MatchError("unexpected status code 401"))
+		Expect(restager.refreshToken).To(Equal("unchanged"))
+	})
+
+	It("retries the request once with a new token after a 401", func() {
+		fetcher.tokens = []string{"some-token", "some-other-token"}
+		fetcher.refTokens = []string{"some-ref-token", "some-ref-token"}
+		fetcher.errs = []error{nil, nil}
+		doer.statusCodes = []int{http.StatusUnauthorized}
+		doer.respBody = "resp-body"
+
+		body, err := c.Curl("/v2/some-url", "PUT", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("resp-body"))
+
+		Expect(doer.headers).To(HaveLen(2))
+		Expect(doer.headers[0].Get("Authorization")).To(Equal("some-token"))
+		Expect(doer.headers[1].Get("Authorization")).To(Equal("some-other-token"))
+		Expect(doer.bodies).To(Equal([]string{"some-body", "some-body"}))
+	})
+
+	It("saves the refresh token UAA returns with the first token", func() {
+		fetcher.tokens = []string{"some-token"}
+		fetcher.refTokens = []string{"some-other-ref-token"}
+		fetcher.errs = []error{nil}
+
+		_, err := c.Curl("some-url", "PUT", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restager.refreshToken).To(Equal("some-other-ref-token"))
+	})
+
+	It("does not save the refresh token if it did not change", func() {
+		fetcher.tokens = []string{"some-token", "some-other-token"}
+		fetcher.refTokens = []string{"some-ref-token", "some-ref-token"}
+		fetcher.errs = []error{nil, nil}
+		doer.statusCodes = []int{http.StatusUnauthorized}
+		restager.refreshToken = "unchanged"
+
+		_, err := c.Curl("some-url", "PUT", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restager.refreshToken).To(Equal("unchanged"))
+	})
+
+	It("refreshes a token that is about to expire", func() {
+		expired := jwt(time.Now().Add(10 * time.Second))
+		fetcher.tokens = []string{expired, "some-other-token"}
+		fetcher.refTokens = []string{"some-ref-token", "some-ref-token"}
+		fetcher.errs = []error{nil, nil}
+
+		_, err := c.Curl("some-url", "PUT", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = c.Curl("some-url", "PUT", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fetcher.called).To(Equal(2))
+		Expect(doer.headers[1].Get("Authorization")).To(Equal("some-other-token"))
+	})
+
+	It("reuses a token that does not expire soon", func() {
+		valid := "bearer " + jwt(time.Now().Add(time.Hour))
+		fetcher.tokens = []string{valid}
+		fetcher.refTokens = []string{"some-ref-token"}
+		fetcher.errs = []error{nil}
+
+		_, err := c.Curl("some-url", "PUT", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = c.Curl("some-url", "PUT", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fetcher.called).To(Equal(1))
+		Expect(doer.headers[1].Get("Authorization")).To(Equal(valid))
+	})
+
+	It("refreshes the token once for concurrent 401s", func() {
+		authDoer := &authorizingDoer{token: "some-other-token"}
+		fetcher.tokens = []string{"some-token", "some-other-token"}
+		fetcher.refTokens = []string{"some-ref-token", "some-other-ref-token"}
+		fetcher.errs = []error{nil, nil}
+		c = cloudcontroller.NewHTTPCurlClient("https://api.system-domain.com", authDoer, fetcher, restager)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+
+				_, err := c.Curl("some-url", "PUT", "some-body")
+				Expect(err).ToNot(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+
+		Expect(fetcher.called).To(Equal(2))
+		Expect(restager.refreshToken).To(Equal("some-other-ref-token"))
+	})
+
+	It("lets the refresh token be saved with the new token", func() {
+		fetcher.tokens = []string{"some-token", jwt(time.Now())}
+		fetcher.refTokens = []string{"some-ref-token", "some-other-ref-token"}
+		fetcher.errs = []error{nil, nil}
+		doer.statusCodes = []int{http.StatusUnauthorized}
+
+		var saveErr error
+		c = cloudcontroller.NewHTTPCurlClient("https://api.system-domain.com", doer, fetcher, cloudcontroller.SaveAndRestagerFunc(func(string) error {
+			_, saveErr = c.Curl("/v3/apps/app-guid/environment_variables", "PATCH", "some-body")
+			return saveErr
+		}))
+
+		_, err := c.Curl("some-url", "PUT", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(saveErr).ToNot(HaveOccurred())
+		Expect(fetcher.called).To(Equal(2))
+	})
+
+	It("refreshes the token again if saving the refresh token gets a 401", func() {
+		fetcher.tokens = []string{"some-token", "some-other-token", "another-token"}
+		fetcher.refTokens = []string{"some-ref-token", "some-other-ref-token", "some-other-ref-token"}
+		fetcher.errs = []error{nil, nil, nil}
+		doer.statusCodes = []int{http.StatusUnauthorized, http.StatusUnauthorized}
+
+		var (
+			saved   []string
+			saveErr error
+		)
+		c = cloudcontroller.NewHTTPCurlClient("https://api.system-domain.com", doer, fetcher, cloudcontroller.SaveAndRestagerFunc(func(refToken string) error {
+			saved = append(saved, refToken)
+			_, saveErr = c.Curl("/v3/apps/app-guid/environment_variables", "PATCH", "some-body")
+			return saveErr
+		}), cloudcontroller.WithHTTPCurlClientRefreshToken("some-ref-token"))
+
+		done := make(chan error)
+		go func() {
+			_, err := c.Curl("some-url", "PUT", "some-body")
+			done <- err
+		}()
+
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(saveErr).ToNot(HaveOccurred())
+		Expect(saved).To(Equal([]string{"some-other-ref-token"}))
+		Expect(fetcher.called).To(Equal(3))
+		Expect(doer.headers).To(HaveLen(4))
+		Expect(doer.headers[2].Get("Authorization")).To(Equal("another-token"))
+		Expect(doer.headers[3].Get("Authorization")).To(Equal("another-token"))
+	})
+
 	It("returns an error if the TokenFetcher fails", func() {
 		fetcher.tokens = []string{""}
 		fetcher.refTokens = []string{""}
@@ -137,9 +311,10 @@ type spyDoer struct {
 	headers []http.Header
 	users   []*url.Userinfo
 
-	statusCode int
-	err        error
-	respBody   string
+	statusCode  int
+	statusCodes []int
+	err         error
+	respBody    string
 }
 
 func newSpyDoer() *spyDoer {
@@ -168,12 +343,41 @@ func (s *spyDoer) Do(r *http.Request) (*http.Response, error) {
 
 	s.bodies = append(s.bodies, string(body))
 
+	statusCode := s.statusCode
+	if len(s.statusCodes) > 0 {
+		statusCode = s.statusCodes[0]
+		s.statusCodes = s.statusCodes[1:]
+	}
+
 	return &http.Response{
-		StatusCode: s.statusCode,
+		StatusCode: statusCode,
 		Body:       ioutil.NopCloser(strings.NewReader(s.respBody)),
 	}, s.err
 }
 
+// authorizingDoer responds with a 401 to requests without the given token.
+type authorizingDoer struct {
+	token string
+}
+
+func (d *authorizingDoer) Do(r *http.Request) (*http.Response, error) {
+	statusCode := http.StatusOK
+	if r.Header.Get("Authorization") != d.token {
+		statusCode = http.StatusUnauthorized
+	}
+
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil
+}
+
+// jwt returns an unsigned JWT that expires at the given time.
+func jwt(exp time.Time) string {
+	payload := fmt.Sprintf(`{"exp": %d}`, exp.Unix())
+	return "e30." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2ln"
+}
+
 type spyTokenFetcher struct {
 	mu sync.Mutex
 
@@ -217,15 +421,17 @@ func (s *spyTokenFetcher) Token() (string, string, error) {
 type spySaveAndRestager struct {
 	mu           sync.Mutex
 	refreshToken string
+	err          error
 }
 
 func newSpySaveAndRestager() *spySaveAndRestager {
 	return &spySaveAndRestager{}
 }
 
-func (s *spySaveAndRestager) SaveAndRestage(refreshToken string) {
+func (s *spySaveAndRestager) SaveAndRestage(refreshToken string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.refreshToken = refreshToken
+	return s.err
 }
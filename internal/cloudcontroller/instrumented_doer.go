@@ -0,0 +1,42 @@
+package cloudcontroller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/metrics"
+)
+
+// InstrumentedDoer counts the requests made to Cloud Controller by method
+// and status code and records their latency by method. Requests that fail
+// without a response are counted with the code "error".
+type InstrumentedDoer struct {
+	d        Doer
+	requests *metrics.Counter
+	latency  *metrics.Histogram
+}
+
+// NewInstrumentedDoer wraps the Doer. The requests counter must have the
+// labels method and code, the latency histogram the label method.
+func NewInstrumentedDoer(d Doer, requests *metrics.Counter, latency *metrics.Histogram) *InstrumentedDoer {
+	return &InstrumentedDoer{
+		d:        d,
+		requests: requests,
+		latency:  latency,
+	}
+}
+
+func (d *InstrumentedDoer) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := d.d.Do(req)
+	d.latency.Observe(time.Since(start).Seconds(), req.Method)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	d.requests.Inc(req.Method, code)
+
+	return resp, err
+}
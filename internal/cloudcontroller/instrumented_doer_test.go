@@ -0,0 +1,53 @@
+package cloudcontroller_test
+
+import (
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+	"code.cloudfoundry.org/cf-drain-cli/internal/metrics"
+)
+
+var _ = Describe("InstrumentedDoer", func() {
+	var (
+		spyDoer  *spyDoer
+		requests *metrics.Counter
+		latency  *metrics.Histogram
+		d        *cloudcontroller.InstrumentedDoer
+	)
+
+	BeforeEach(func() {
+		spyDoer = newSpyDoer()
+		r := metrics.NewRegistry()
+		requests = r.NewCounter("requests_total", "Requests.", "method", "code")
+		latency = r.NewHistogram("request_duration_seconds", "Latency.", nil, "method")
+		d = cloudcontroller.NewInstrumentedDoer(spyDoer, requests, latency)
+	})
+
+	It("counts requests by method and status code", func() {
+		req, _ := http.NewRequest("GET", "https://api.example.com/v3/apps", nil)
+		spyDoer.statusCode = 404
+
+		resp, err := d.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(404))
+
+		Expect(spyDoer.URLs).To(ConsistOf("https://api.example.com/v3/apps"))
+		Expect(requests.Value("GET", "404")).To(Equal(1.0))
+		Expect(latency.Count("GET")).To(Equal(uint64(1)))
+	})
+
+	It("counts failed requests with an error code", func() {
+		req, _ := http.NewRequest("POST", "https://api.example.com/v3/apps", nil)
+		spyDoer.err = errors.New("some-error")
+
+		_, err := d.Do(req)
+		Expect(err).To(MatchError("some-error"))
+
+		Expect(requests.Value("POST", "error")).To(Equal(1.0))
+		Expect(latency.Count("POST")).To(Equal(uint64(1)))
+	})
+})
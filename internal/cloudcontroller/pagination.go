@@ -0,0 +1,29 @@
+package cloudcontroller
+
+import "net/url"
+
+// Pagination is the pagination section of Cloud Controller v3 list
+// responses.
+type Pagination struct {
+	Next Link `json:"next"`
+}
+
+type Link struct {
+	Href string `json:"href"`
+}
+
+// Path returns the path and query of the link. Cloud Controller returns
+// absolute URLs, while Curlers expect them relative to the API address. It
+// returns an empty string for an empty link.
+func (l Link) Path() string {
+	if l.Href == "" {
+		return ""
+	}
+
+	u, err := url.Parse(l.Href)
+	if err != nil {
+		return l.Href
+	}
+
+	return u.RequestURI()
+}
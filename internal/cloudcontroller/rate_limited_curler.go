@@ -0,0 +1,64 @@
+package cloudcontroller
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimitedCurler limits the rate of requests to Cloud Controller. Up to
+// burst requests are made at once, after that requests are delayed to stay
+// within the rate.
+type RateLimitedCurler struct {
+	c     Curler
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimitedCurler wraps the Curler so that it makes at most
+// requestsPerSecond requests per second on average. A burst below one is
+// treated as one.
+func NewRateLimitedCurler(c Curler, requestsPerSecond float64, burst int) *RateLimitedCurler {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimitedCurler{
+		c:      c,
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (c *RateLimitedCurler) Curl(URL, method, body string) ([]byte, error) {
+	time.Sleep(c.reserve())
+
+	return c.c.Curl(URL, method, body)
+}
+
+// reserve takes a token from the bucket and returns how long to wait until
+// the token is available. Tokens may be taken before they are available so
+// concurrent requests queue up in order.
+func (c *RateLimitedCurler) reserve() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.tokens += now.Sub(c.last).Seconds() * c.rate
+	if c.tokens > c.burst {
+		c.tokens = c.burst
+	}
+	c.last = now
+
+	c.tokens--
+	if c.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-c.tokens / c.rate * float64(time.Second))
+}
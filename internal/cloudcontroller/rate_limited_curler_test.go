@@ -0,0 +1,71 @@
+package cloudcontroller_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+)
+
+var _ = Describe("RateLimitedCurler", func() {
+	var (
+		curler *stubCurler
+	)
+
+	BeforeEach(func() {
+		curler = newStubCurler()
+		curler.resps["/v3/apps"] = "some-response"
+	})
+
+	It("passes requests through", func() {
+		c := cloudcontroller.NewRateLimitedCurler(curler, 100, 1)
+
+		resp, err := c.Curl("/v3/apps", "POST", "some-body")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(resp)).To(Equal("some-response"))
+		Expect(curler.URLs).To(ConsistOf("/v3/apps"))
+		Expect(curler.methods).To(ConsistOf("POST"))
+		Expect(curler.bodies).To(ConsistOf("some-body"))
+	})
+
+	It("returns the errors of the wrapped curler", func() {
+		curler.errs["/v3/apps"] = errors.New("some-error")
+		c := cloudcontroller.NewRateLimitedCurler(curler, 100, 1)
+
+		_, err := c.Curl("/v3/apps", "GET", "")
+		Expect(err).To(MatchError("some-error"))
+	})
+
+	It("does not delay requests within the burst", func() {
+		c := cloudcontroller.NewRateLimitedCurler(curler, 1, 5)
+
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			c.Curl("/v3/apps", "GET", "")
+		}
+		Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+	})
+
+	It("delays concurrent requests beyond the burst to stay within the rate", func() {
+		c := cloudcontroller.NewRateLimitedCurler(curler, 50, 1)
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Curl("/v3/apps", "GET", "")
+			}()
+		}
+		wg.Wait()
+
+		// The first request is made at once, the other five at 20ms
+		// intervals.
+		Expect(time.Since(start)).To(BeNumerically(">=", 90*time.Millisecond))
+	})
+})
@@ -7,39 +7,41 @@ import (
 
 type Restager struct {
 	c       AuthCurler
-	log     Logger
+	s       CredentialStore
 	appGUID string
 }
 
-func NewRestager(appGUID string, c AuthCurler, log Logger) *Restager {
+func NewRestager(appGUID string, s CredentialStore, c AuthCurler) *Restager {
 	return &Restager{
 		c:       c,
-		log:     log,
+		s:       s,
 		appGUID: appGUID,
 	}
 }
 
-func (r *Restager) SaveAndRestage(refreshToken string) {
-	r.saveRefreshToken(refreshToken)
-	r.restageApp()
-}
+// SaveAndRestage saves the refresh token in the credential store and
+// restages the app if the store requires it.
+func (r *Restager) SaveAndRestage(refreshToken string) error {
+	if err := r.s.SaveRefreshToken(refreshToken); err != nil {
+		return err
+	}
 
-func (r *Restager) saveRefreshToken(refreshToken string) {
-	url := fmt.Sprintf("/v3/apps/%s/environment_variables", r.appGUID)
-	body := fmt.Sprintf(`{"var":{"REFRESH_TOKEN": %q}}`, refreshToken)
-	_, err := r.c.Curl(url, http.MethodPatch, body)
-	if err != nil {
-		r.log.Fatalf("Failed to updated REFRESH_TOKEN with cloud controller: %s", err)
+	if !r.s.RequiresRestage() {
+		return nil
 	}
+
+	return r.restageApp()
 }
 
 // Restage to enable the app to start with the new refresh token. This
 // ensures that if the app crashes or gets restarted, it will have proper
 // state.
-func (r *Restager) restageApp() {
+func (r *Restager) restageApp() error {
 	url := fmt.Sprintf("/v2/apps/%s/restage", r.appGUID)
 	_, err := r.c.Curl(url, http.MethodPost, "")
 	if err != nil {
-		r.log.Fatalf("Failed to restage app: %s", err)
+		return fmt.Errorf("failed to restage app: %s", err)
 	}
+
+	return nil
 }
@@ -11,19 +11,18 @@ import (
 
 var _ = Describe("Restager", func() {
 	var (
-		r          *cloudcontroller.Restager
-		ac         *spyAuthCurler
-		stubLogger *stubLogger
+		r  *cloudcontroller.Restager
+		ac *spyAuthCurler
 	)
 
 	BeforeEach(func() {
 		ac = &spyAuthCurler{}
-		stubLogger = newStubLogger()
-		r = cloudcontroller.NewRestager("app-guid", ac, stubLogger)
+		r = cloudcontroller.NewRestager("app-guid", cloudcontroller.NewEnvCredentialStore("app-guid", ac), ac)
 	})
 
 	It("saves new refresh token and restage", func() {
-		r.SaveAndRestage("new-refresh-token")
+		err := r.SaveAndRestage("new-refresh-token")
+		Expect(err).ToNot(HaveOccurred())
 
 		Expect(ac.urls).To(HaveLen(2))
 
@@ -40,15 +39,39 @@ var _ = Describe("Restager", func() {
 		Expect(ac.bodies[1]).To(Equal(""))
 	})
 
-	It("panics if unable to save REFRESH_TOKEN to cloud controller", func() {
+	It("returns an error if unable to save REFRESH_TOKEN to cloud controller", func() {
 		ac.errs = []error{errors.New("CAPI is down")}
-		Expect(func() { r.SaveAndRestage("some-token") }).To(Panic())
-		Expect(stubLogger.called).To(Equal(1))
+		err := r.SaveAndRestage("some-token")
+		Expect(err).To(MatchError("failed to update REFRESH_TOKEN with cloud controller: CAPI is down"))
+		Expect(ac.urls).To(HaveLen(1))
 	})
 
-	It("panics if unable to restage app", func() {
+	It("returns an error if unable to restage app", func() {
 		ac.errs = []error{nil, errors.New("CAPI is down")}
-		Expect(func() { r.SaveAndRestage("some-token") }).To(Panic())
-		Expect(stubLogger.called).To(Equal(1))
+		err := r.SaveAndRestage("some-token")
+		Expect(err).To(MatchError("failed to restage app: CAPI is down"))
+	})
+
+	It("does not restage if the credential store does not require it", func() {
+		service := cloudcontroller.BoundService{Name: "drain-credentials", InstanceGUID: "service-guid"}
+		r = cloudcontroller.NewRestager("app-guid", cloudcontroller.NewCredHubServiceCredentialStore(service, ac), ac)
+
+		err := r.SaveAndRestage("new-refresh-token")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ac.urls).To(Equal([]string{"/v3/service_instances/service-guid"}))
+	})
+
+	It("restages after saving the refresh token in a user-provided service", func() {
+		service := cloudcontroller.BoundService{Name: "drain-credentials", InstanceGUID: "service-guid"}
+		r = cloudcontroller.NewRestager("app-guid", cloudcontroller.NewUserProvidedServiceCredentialStore(service, ac), ac)
+
+		err := r.SaveAndRestage("new-refresh-token")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ac.urls).To(Equal([]string{
+			"/v3/service_instances/service-guid",
+			"/v2/apps/app-guid/restage",
+		}))
 	})
 })
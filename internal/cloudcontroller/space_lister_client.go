@@ -0,0 +1,57 @@
+package cloudcontroller
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+type Space struct {
+	Name string
+	Guid string
+}
+
+type SpaceListerClient struct {
+	c Curler
+}
+
+func NewSpaceListerClient(c Curler) *SpaceListerClient {
+	return &SpaceListerClient{
+		c: c,
+	}
+}
+
+// ListSpaces returns every space in the org.
+func (c *SpaceListerClient) ListSpaces(orgGuid string) ([]Space, error) {
+	params := url.Values{
+		"organization_guids": {orgGuid},
+	}
+
+	var spaces []Space
+	url := "/v3/spaces?" + params.Encode()
+	for url != "" {
+		resp, err := c.c.Curl(url, "GET", "")
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Pagination Pagination `json:"pagination"`
+			Resources  []struct {
+				Guid string `json:"guid"`
+				Name string `json:"name"`
+			} `json:"resources"`
+		}
+		err = json.Unmarshal(resp, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range page.Resources {
+			spaces = append(spaces, Space{r.Name, r.Guid})
+		}
+
+		url = page.Pagination.Next.Path()
+	}
+
+	return spaces, nil
+}
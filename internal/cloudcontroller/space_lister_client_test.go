@@ -0,0 +1,65 @@
+package cloudcontroller_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+)
+
+var _ = Describe("SpaceListerClient", func() {
+	var (
+		curler *stubCurler
+		c      *cloudcontroller.SpaceListerClient
+	)
+
+	BeforeEach(func() {
+		curler = newStubCurler()
+		c = cloudcontroller.NewSpaceListerClient(curler)
+	})
+
+	It("requests every page of spaces in the org", func() {
+		curler.resps["/v3/spaces?organization_guids=some-org"] = `
+		{
+			"pagination": {
+				"next": {"href": "https://api.example.com/v3/spaces?organization_guids=some-org&page=2"}
+			},
+			"resources": [
+				{"guid": "a", "name": "space-1"}
+			]
+		}
+		`
+		curler.resps["/v3/spaces?organization_guids=some-org&page=2"] = `
+		{
+			"pagination": {
+				"next": null
+			},
+			"resources": [
+				{"guid": "b", "name": "space-2"}
+			]
+		}
+		`
+
+		spaces, err := c.ListSpaces("some-org")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.methods).To(ConsistOf("GET", "GET"))
+		Expect(spaces).To(Equal([]cloudcontroller.Space{
+			{Name: "space-1", Guid: "a"},
+			{Name: "space-2", Guid: "b"},
+		}))
+	})
+
+	It("returns an error if the GET fails", func() {
+		curler.errs["/v3/spaces?organization_guids=some-org"] = errors.New("some-error")
+		_, err := c.ListSpaces("some-org")
+		Expect(err).To(MatchError("some-error"))
+	})
+
+	It("returns an error if the JSON is invalid", func() {
+		curler.resps["/v3/spaces?organization_guids=some-org"] = `invalid`
+		_, err := c.ListSpaces("some-org")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,5 +1,12 @@
 package cloudcontroller
 
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
 type AuthCurler interface {
 	Curl(url, method, body string) ([]byte, error)
 }
@@ -9,16 +16,141 @@ type UAAClient interface {
 }
 
 type Logger interface {
+	Printf(format string, v ...interface{})
 	Fatalf(format string, v ...interface{})
 }
 
+// TokenErrorCause classifies why tokens could not be fetched from UAA.
+type TokenErrorCause string
+
+const (
+	// TokenUnavailable means UAA could not be reached or failed, e.g. with
+	// a 5XX. Fetching the tokens again may succeed.
+	TokenUnavailable TokenErrorCause = "unavailable"
+
+	// TokenRejected means UAA rejected the request, e.g. because the
+	// refresh token expired or was revoked. Fetching the tokens again will
+	// fail the same way.
+	TokenRejected TokenErrorCause = "rejected"
+
+	// TokenInvalidResponse means UAA responded with something that is not
+	// a token.
+	TokenInvalidResponse TokenErrorCause = "invalid response"
+)
+
+// TokenError is returned by the TokenManager when tokens could not be
+// fetched from UAA.
+type TokenError struct {
+	Cause TokenErrorCause
+	Err   error
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("failed to fetch tokens from UAA (%s): %s", e.Cause, e.Err)
+}
+
+// Retryable reports whether fetching the tokens again may succeed.
+func (e *TokenError) Retryable() bool {
+	return e.Cause != TokenRejected
+}
+
+// classifyTokenError classifies an error of the uaago client, which only
+// returns formatted errors.
+func classifyTokenError(err error) *TokenError {
+	msg := err.Error()
+	switch {
+	case strings.HasPrefix(msg, "Unexpected status code: 4"),
+		strings.HasPrefix(msg, "Received a status code 4"):
+		return &TokenError{Cause: TokenRejected, Err: err}
+	case strings.HasPrefix(msg, "Unable to decode"), strings.HasPrefix(msg, "Missing"):
+		return &TokenError{Cause: TokenInvalidResponse, Err: err}
+	default:
+		return &TokenError{Cause: TokenUnavailable, Err: err}
+	}
+}
+
+// TokenRetryPolicy configures how the TokenManager retries failed token
+// fetches.
+type TokenRetryPolicy struct {
+	// Attempts is how often the tokens are fetched before Token returns
+	// the error. Rejected fetches are not retried.
+	Attempts int
+
+	// Backoff is the delay before the second attempt. It doubles with
+	// every further attempt, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// MaxConsecutiveFailures is the number of failed attempts in a row,
+	// across calls to Token, after which the process exits. Zero never
+	// exits.
+	MaxConsecutiveFailures int
+}
+
+// defaultTokenRetryPolicy does not retry a failed fetch and exits.
+var defaultTokenRetryPolicy = TokenRetryPolicy{
+	Attempts:               1,
+	MaxConsecutiveFailures: 1,
+}
+
+// tokenRetrier fetches tokens according to a TokenRetryPolicy. It is not
+// safe for concurrent use.
+type tokenRetrier struct {
+	log      Logger
+	policy   TokenRetryPolicy
+	failures int
+}
+
+// fetch calls f until it succeeds, fails with an error that can not be
+// retried or the attempts are used up. Errors are of type *TokenError.
+func (r *tokenRetrier) fetch(f func() (string, string, error)) (string, string, error) {
+	backoff := r.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		accToken, refToken, err := f()
+		if err == nil {
+			r.failures = 0
+			return accToken, refToken, nil
+		}
+
+		tokenErr := classifyTokenError(err)
+		r.failures++
+		r.log.Printf("Attempt %d to fetch tokens from UAA failed: %s", attempt, tokenErr)
+
+		if r.policy.MaxConsecutiveFailures > 0 && r.failures >= r.policy.MaxConsecutiveFailures {
+			r.log.Fatalf("Failed to fetch tokens from UAA %d times in a row: %s", r.failures, tokenErr)
+		}
+
+		if attempt >= r.policy.Attempts || !tokenErr.Retryable() {
+			return "", "", tokenErr
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if r.policy.MaxBackoff > 0 && backoff > r.policy.MaxBackoff {
+			backoff = r.policy.MaxBackoff
+		}
+	}
+}
+
+type TokenManagerOption func(*TokenManager)
+
+// WithTokenRetryPolicy sets the retry policy. By default a failed fetch is
+// not retried and the process exits.
+func WithTokenRetryPolicy(p TokenRetryPolicy) TokenManagerOption {
+	return func(m *TokenManager) {
+		m.retry.policy = p
+	}
+}
+
 type TokenManager struct {
 	uaa                UAAClient
 	clientID           string
-	refreshToken       string
 	appGUID            string
 	insecureSkipVerify bool
-	log                Logger
+
+	mu           sync.Mutex
+	refreshToken string
+	retry        tokenRetrier
 }
 
 func NewTokenManager(
@@ -28,24 +160,40 @@ func NewTokenManager(
 	appGUID string,
 	skipCertVerify bool,
 	log Logger,
-
+	opts ...TokenManagerOption,
 ) *TokenManager {
-	return &TokenManager{
+	m := &TokenManager{
 		uaa:                uaa,
 		clientID:           clientID,
 		refreshToken:       initialRefreshToken,
 		appGUID:            appGUID,
 		insecureSkipVerify: skipCertVerify,
-		log:                log,
+		retry: tokenRetrier{
+			log:    log,
+			policy: defaultTokenRetryPolicy,
+		},
 	}
+
+	for _, o := range opts {
+		o(m)
+	}
+
+	return m
 }
 
+// Token fetches an access token with the refresh token. The refresh token
+// is replaced by the one UAA returns. Errors are of type *TokenError.
 func (m *TokenManager) Token() (string, string, error) {
-	refToken, accToken, err := m.uaa.GetRefreshToken(m.clientID, m.refreshToken, m.insecureSkipVerify)
-	if err != nil {
-		m.log.Fatalf("Failed to fetch tokens from UAA: %s", err)
-	}
-	m.refreshToken = refToken
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.retry.fetch(func() (string, string, error) {
+		refToken, accToken, err := m.uaa.GetRefreshToken(m.clientID, m.refreshToken, m.insecureSkipVerify)
+		if err != nil {
+			return "", "", err
+		}
 
-	return accToken, refToken, nil
+		m.refreshToken = refToken
+		return accToken, refToken, nil
+	})
 }
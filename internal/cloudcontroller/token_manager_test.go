@@ -2,6 +2,8 @@ package cloudcontroller_test
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -67,9 +69,105 @@ var _ = Describe("TokenManager", func() {
 		Expect(uaa.reqSkipCertVerify).To(BeFalse())
 	})
 
-	It("returns an error if UAA fails", func() {
+	It("exits if UAA fails without a retry policy", func() {
 		uaa.respError = errors.New("uaa-error")
 		Expect(func() { m.Token() }).To(Panic())
+		Expect(stubLogger.printfMessages).To(ConsistOf(
+			"Attempt 1 to fetch tokens from UAA failed: failed to fetch tokens from UAA (unavailable): uaa-error",
+		))
+	})
+
+	Context("with a retry policy", func() {
+		BeforeEach(func() {
+			m = cloudcontroller.NewTokenManager(
+				uaa,
+				"client-id",
+				"initial-refresh-token",
+				"app-guid",
+				false,
+				stubLogger,
+				cloudcontroller.WithTokenRetryPolicy(cloudcontroller.TokenRetryPolicy{
+					Attempts:               3,
+					Backoff:                time.Millisecond,
+					MaxBackoff:             2 * time.Millisecond,
+					MaxConsecutiveFailures: 5,
+				}),
+			)
+		})
+
+		It("retries and returns a classified error", func() {
+			uaa.respError = errors.New("Unable to make request: connection refused")
+
+			_, _, err := m.Token()
+			Expect(err).To(MatchError("failed to fetch tokens from UAA (unavailable): Unable to make request: connection refused"))
+
+			tokenErr, ok := err.(*cloudcontroller.TokenError)
+			Expect(ok).To(BeTrue())
+			Expect(tokenErr.Cause).To(Equal(cloudcontroller.TokenUnavailable))
+			Expect(tokenErr.Retryable()).To(BeTrue())
+
+			Expect(uaa.calls).To(Equal(3))
+			Expect(stubLogger.printfMessages).To(HaveLen(3))
+			Expect(stubLogger.printfMessages[2]).To(HavePrefix("Attempt 3 to fetch tokens from UAA failed: "))
+			Expect(stubLogger.called).To(BeZero())
+		})
+
+		It("recovers when UAA comes back", func() {
+			uaa.errs = []error{errors.New("Unexpected status code: 503")}
+
+			token, _, err := m.Token()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token).To(Equal("access-token"))
+			Expect(uaa.calls).To(Equal(2))
+		})
+
+		It("does not retry rejected refresh tokens", func() {
+			uaa.respError = errors.New("Unexpected status code: 401")
+
+			_, _, err := m.Token()
+			Expect(err.(*cloudcontroller.TokenError).Cause).To(Equal(cloudcontroller.TokenRejected))
+			Expect(uaa.calls).To(Equal(1))
+		})
+
+		It("classifies invalid responses", func() {
+			uaa.respError = errors.New("Missing access_token in response body")
+
+			_, _, err := m.Token()
+			Expect(err.(*cloudcontroller.TokenError).Cause).To(Equal(cloudcontroller.TokenInvalidResponse))
+		})
+
+		It("exits after the max consecutive failures", func() {
+			uaa.respError = errors.New("Unexpected status code: 500")
+
+			_, _, err := m.Token()
+			Expect(err).To(HaveOccurred())
+
+			Expect(func() { m.Token() }).To(Panic())
+			Expect(uaa.calls).To(Equal(5))
+			Expect(stubLogger.called).To(Equal(1))
+		})
+
+		It("resets the consecutive failures on success", func() {
+			uaa.errs = []error{
+				errors.New("Unexpected status code: 500"),
+				errors.New("Unexpected status code: 500"),
+				errors.New("Unexpected status code: 500"),
+				nil,
+				errors.New("Unexpected status code: 500"),
+				errors.New("Unexpected status code: 500"),
+				errors.New("Unexpected status code: 500"),
+			}
+
+			_, _, err := m.Token()
+			Expect(err).To(HaveOccurred())
+
+			_, _, err = m.Token()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, err = m.Token()
+			Expect(err).To(HaveOccurred())
+			Expect(stubLogger.called).To(BeZero())
+		})
 	})
 
 	It("does not overwrite the refresh token if GetRefreshToken fails", func() {
@@ -86,6 +184,7 @@ var _ = Describe("TokenManager", func() {
 })
 
 type spyUAAClient struct {
+	calls             int
 	reqClientID       string
 	reqRefreshToken   string
 	reqSkipCertVerify bool
@@ -93,12 +192,24 @@ type spyUAAClient struct {
 	respRefreshToken string
 	respAccessToken  string
 	respError        error
+
+	// errs are returned by the first calls, before respError.
+	errs []error
 }
 
 func (s *spyUAAClient) GetRefreshToken(clientID, refreshToken string, insecureSkipVerify bool) (string, string, error) {
 	s.reqClientID = clientID
 	s.reqRefreshToken = refreshToken
 	s.reqSkipCertVerify = insecureSkipVerify
+	s.calls++
+
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		if err != nil {
+			return "", "", err
+		}
+	}
 
 	return s.respRefreshToken, s.respAccessToken, s.respError
 }
@@ -126,13 +237,18 @@ func (s *spyAuthCurler) Curl(url string, method string, body string) ([]byte, er
 }
 
 type stubLogger struct {
-	called int
+	called         int
+	printfMessages []string
 }
 
 func newStubLogger() *stubLogger {
 	return &stubLogger{}
 }
 
+func (s *stubLogger) Printf(format string, v ...interface{}) {
+	s.printfMessages = append(s.printfMessages, fmt.Sprintf(format, v...))
+}
+
 func (s *stubLogger) Fatalf(format string, v ...interface{}) {
 	s.called++
 	panic("fatal")
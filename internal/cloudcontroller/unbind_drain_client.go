@@ -0,0 +1,50 @@
+package cloudcontroller
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+type UnbindDrainClient struct {
+	c Curler
+}
+
+func NewUnbindDrainClient(c Curler) *UnbindDrainClient {
+	return &UnbindDrainClient{
+		c: c,
+	}
+}
+
+// UnbindDrain deletes the binding between the app and the drain. It does
+// nothing if the app is not bound to the drain.
+func (c *UnbindDrainClient) UnbindDrain(appGuid, serviceInstanceGuid string) error {
+	params := url.Values{
+		"type":                   {"app"},
+		"app_guids":              {appGuid},
+		"service_instance_guids": {serviceInstanceGuid},
+	}
+
+	resp, err := c.c.Curl("/v3/service_credential_bindings?"+params.Encode(), "GET", "")
+	if err != nil {
+		return err
+	}
+
+	var bindings struct {
+		Resources []struct {
+			Guid string `json:"guid"`
+		} `json:"resources"`
+	}
+	err = json.Unmarshal(resp, &bindings)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bindings.Resources {
+		_, err := c.c.Curl("/v3/service_credential_bindings/"+b.Guid, "DELETE", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package cloudcontroller_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
+)
+
+var _ = Describe("UnbindDrainClient", func() {
+	var (
+		curler *stubCurler
+		c      *cloudcontroller.UnbindDrainClient
+		key    string
+	)
+
+	BeforeEach(func() {
+		curler = newStubCurler()
+		c = cloudcontroller.NewUnbindDrainClient(curler)
+		key = "/v3/service_credential_bindings?app_guids=app-guid&service_instance_guids=drain-guid&type=app"
+	})
+
+	It("deletes the binding between the app and the drain", func() {
+		curler.resps[key] = `{"resources": [{"guid": "binding-guid"}]}`
+
+		err := c.UnbindDrain("app-guid", "drain-guid")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.URLs).To(Equal([]string{
+			key,
+			"/v3/service_credential_bindings/binding-guid",
+		}))
+		Expect(curler.methods).To(Equal([]string{"GET", "DELETE"}))
+	})
+
+	It("does nothing if the app is not bound", func() {
+		curler.resps[key] = `{"resources": []}`
+
+		err := c.UnbindDrain("app-guid", "drain-guid")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(curler.methods).To(Equal([]string{"GET"}))
+	})
+
+	It("returns an error if the GET fails", func() {
+		curler.errs[key] = errors.New("some-error")
+
+		err := c.UnbindDrain("app-guid", "drain-guid")
+		Expect(err).To(MatchError("some-error"))
+	})
+
+	It("returns an error if the JSON is invalid", func() {
+		curler.resps[key] = `invalid`
+
+		err := c.UnbindDrain("app-guid", "drain-guid")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns an error if the DELETE fails", func() {
+		curler.resps[key] = `{"resources": [{"guid": "binding-guid"}]}`
+		curler.errs["/v3/service_credential_bindings/binding-guid"] = errors.New("some-error")
+
+		err := c.UnbindDrain("app-guid", "drain-guid")
+		Expect(err).To(MatchError("some-error"))
+	})
+})
@@ -0,0 +1,282 @@
+package command
+
+import (
+	"sort"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+	"code.cloudfoundry.org/cf-drain-cli/internal/drainurl"
+	"code.cloudfoundry.org/cli/plugin"
+	flags "github.com/jessevdk/go-flags"
+)
+
+type applyDrainsOpts struct {
+	File           string `long:"file" short:"f"`
+	Prune          bool   `long:"prune"`
+	SkipValidation bool   `long:"skip-validation"`
+}
+
+// planStep is a single change made by `drains apply`. It either runs a cf
+// command or, for changes that need more than one, calls run.
+type planStep struct {
+	description string
+	command     []string
+	run         func()
+}
+
+// ApplyDrains converges the drains in the targeted space to the drains in a
+// manifest. It prints the plan and then runs the cf commands that make up
+// the plan. Space drains in the manifest are pushed if they do not exist
+// yet, the drains they manage are left alone.
+func ApplyDrains(
+	cli plugin.CliConnection,
+	df DrainFetcher,
+	al LabeledAppLister,
+	er AppEnvReader,
+	d Downloader,
+	f RefreshTokenFetcher,
+	args []string,
+	log Logger,
+) {
+	opts := applyDrainsOpts{}
+
+	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	args, err := parser.ParseArgs(args)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if len(args) != 0 {
+		log.Fatalf("Invalid arguments, expected 0, got %d.", len(args))
+	}
+
+	if opts.File == "" {
+		log.Fatalf("A drain manifest is required, use -f to specify one.")
+	}
+
+	m, err := loadDrainManifest(opts.File)
+	if err != nil {
+		log.Fatalf("Invalid drain manifest: %s", err)
+	}
+
+	// Manifests written by `drains export` keep credentials in query
+	// parameters as placeholders, so they are accepted.
+	if !opts.SkipValidation {
+		for _, d := range m.Drains {
+			validateDrainURL(d.URL, log, drainurl.AllowCredentialParams())
+		}
+		for _, sd := range m.SpaceDrains {
+			validateDrainURL(sd.URL, log, drainurl.AllowCredentialParams())
+		}
+	}
+
+	space := currentSpace(cli, log)
+	drains, err := df.Drains(space.Guid)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	desiredApps := make(map[string][]string)
+	for _, md := range m.Drains {
+		desiredApps[md.Name] = manifestDrainApps(cli, al, space.Guid, md, log)
+	}
+
+	var spaceDrains []spaceDrainApp
+	if opts.Prune || len(m.SpaceDrains) > 0 {
+		spaceDrains = spaceDrainApps(cli, er, log)
+	}
+
+	managed := make(map[string]bool)
+	pushed := make(map[string]bool)
+	for _, sd := range spaceDrains {
+		managed[sd.drainName] = true
+		pushed[sd.name] = true
+	}
+	for _, sd := range m.SpaceDrains {
+		managed[sd.Name] = true
+	}
+	// Drains created by space and org drain apps, e.g. the drains an org
+	// drain creates in every space, are recreated by them when removed.
+	for _, d := range drains {
+		if d.CreatedBySpaceDrain() {
+			managed[d.Name] = true
+		}
+	}
+
+	plan := planDrains(m, drains, desiredApps, managed, opts.Prune)
+	for _, sd := range m.SpaceDrains {
+		if pushed[sd.Name] {
+			continue
+		}
+
+		plan = append(plan, pushSpaceDrainStep(cli, sd, opts.SkipValidation, d, f, log))
+	}
+
+	if len(plan) == 0 {
+		log.Printf("No changes, the drains in the space match the manifest.")
+		return
+	}
+
+	log.Printf("Plan:")
+	for _, step := range plan {
+		log.Printf("  %s", step.description)
+	}
+
+	for _, step := range plan {
+		if step.run != nil {
+			step.run()
+			continue
+		}
+
+		_, err := cli.CliCommand(step.command...)
+		if err != nil {
+			log.Fatalf("Failed to %s: %s", step.description, err)
+		}
+	}
+
+	if isDryRun(cli) {
+		log.Printf("Would apply %d changes.", len(plan))
+		return
+	}
+	log.Printf("Applied %d changes.", len(plan))
+}
+
+// manifestDrainApps returns the names of the apps that should be bound to
+// the drain.
+func manifestDrainApps(
+	cli plugin.CliConnection,
+	al LabeledAppLister,
+	spaceGuid string,
+	d manifestDrain,
+	log Logger,
+) []string {
+	apps := append([]string{}, d.Apps...)
+	if !d.hasSelectors() {
+		return apps
+	}
+
+	patterns := make([]appMatcher, 0, len(d.AppPatterns))
+	for _, p := range d.AppPatterns {
+		patterns = append(patterns, compileAppPattern(p, log))
+	}
+
+	return append(apps, selectApps(cli, al, spaceGuid, patterns, d.Labels, log)...)
+}
+
+// pushSpaceDrainStep pushes a space drain app the same way `drain-space`
+// does.
+func pushSpaceDrainStep(
+	cli plugin.CliConnection,
+	sd manifestSpaceDrain,
+	skipValidation bool,
+	d Downloader,
+	f RefreshTokenFetcher,
+	log Logger,
+) planStep {
+	var extraEnvs [][]string
+	if skipValidation {
+		extraEnvs = append(extraEnvs, []string{"SKIP_DRAIN_URL_VALIDATION", "true"})
+	}
+
+	opts := pushSpaceDrainOpts{
+		DrainName:       sd.Name,
+		DrainURL:        sd.URL,
+		DrainType:       sd.Type,
+		CredentialStore: "user-provided",
+	}
+
+	return planStep{
+		description: "push space drain " + sd.Name + " with url " + sanitizeDrainURL(sd.URL),
+		run: func() {
+			pushDrain(cli, sd.Name, "space_drain", "space", extraEnvs, opts, d, f, log)
+		},
+	}
+}
+
+// planDrains computes the steps needed to converge the existing drains to
+// the drains in the manifest. Drains managed by a space drain are never
+// pruned.
+func planDrains(
+	m drainManifest,
+	existing []drain.Drain,
+	desiredApps map[string][]string,
+	managed map[string]bool,
+	prune bool,
+) []planStep {
+	var plan []planStep
+	inManifest := make(map[string]bool)
+
+	for _, d := range m.Drains {
+		inManifest[d.Name] = true
+		apps := uniqueStrings(desiredApps[d.Name])
+
+		current, ok := findDrain(existing, d.Name)
+		if !ok {
+			plan = append(plan, planStep{
+				description: "create drain " + d.Name + " with url " + sanitizeDrainURL(d.URL),
+				command:     []string{"create-user-provided-service", d.Name, "-l", d.URL},
+			})
+		} else if !drainurl.Equal(current.DrainURL, d.URL) {
+			plan = append(plan, planStep{
+				description: "update drain " + d.Name + " to url " + sanitizeDrainURL(d.URL),
+				command:     []string{"update-user-provided-service", d.Name, "-l", d.URL},
+			})
+		}
+
+		for _, app := range apps {
+			if containsString(current.Apps, app) {
+				continue
+			}
+			plan = append(plan, planStep{
+				description: "bind " + app + " to " + d.Name,
+				command:     []string{"bind-service", app, d.Name},
+			})
+		}
+
+		for _, app := range current.Apps {
+			if containsString(apps, app) {
+				continue
+			}
+			plan = append(plan, planStep{
+				description: "unbind " + app + " from " + d.Name,
+				command:     []string{"unbind-service", app, d.Name},
+			})
+		}
+	}
+
+	if !prune {
+		return plan
+	}
+
+	for _, d := range existing {
+		if inManifest[d.Name] || managed[d.Name] {
+			continue
+		}
+
+		for _, app := range d.Apps {
+			plan = append(plan, planStep{
+				description: "unbind " + app + " from " + d.Name,
+				command:     []string{"unbind-service", app, d.Name},
+			})
+		}
+		plan = append(plan, planStep{
+			description: "delete drain " + d.Name,
+			command:     []string{"delete-service", d.Name, "-f"},
+		})
+	}
+
+	return plan
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, v := range values {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		unique = append(unique, v)
+	}
+	sort.Strings(unique)
+	return unique
+}
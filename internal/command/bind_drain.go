@@ -1,43 +1,182 @@
 package command
 
 import (
+	"path"
+	"regexp"
+	"strings"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
 	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
 	"code.cloudfoundry.org/cli/plugin"
+	flags "github.com/jessevdk/go-flags"
 )
 
-func BindDrain(cli plugin.CliConnection, df DrainFetcher, args []string, log Logger) {
-	if len(args) != 2 {
-		log.Fatalf("Invalid arguments, expected 2, got %d.", len(args))
-	}
+// LabeledAppLister lists the apps in a space that match a v3 label
+// selector.
+type LabeledAppLister interface {
+	ListAppsByLabel(spaceGuid, labelSelector string) ([]cloudcontroller.App, error)
+}
 
-	appName := args[0]
-	drainName := args[1]
+type bindDrainOpts struct {
+	AppPatterns []string `long:"app-pattern"`
+	Labels      []string `long:"label"`
+}
+
+// BindDrain binds apps to an existing drain. Apps can be given by name or
+// selected with --app-pattern (a glob, or a regex wrapped in slashes) and
+// --label (a key=value label selector). Each app is bound independently
+// and a failure to bind one app does not stop the others from being bound.
+func BindDrain(cli plugin.CliConnection, df DrainFetcher, al LabeledAppLister, args []string, log Logger) {
+	opts := bindDrainOpts{}
 
-	space, err := cli.GetCurrentSpace()
+	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	args, err := parser.ParseArgs(args)
 	if err != nil {
 		log.Fatalf("%s", err)
 	}
 
+	hasSelectors := len(opts.AppPatterns) > 0 || len(opts.Labels) > 0
+	if hasSelectors && len(args) < 1 {
+		log.Fatalf("Invalid arguments, expected at least 1, got %d.", len(args))
+	}
+	if !hasSelectors && len(args) < 2 {
+		log.Fatalf("Invalid arguments, expected at least 2, got %d.", len(args))
+	}
+
+	appNames := args[:len(args)-1]
+	drainName := args[len(args)-1]
+
+	patterns := make([]appMatcher, 0, len(opts.AppPatterns))
+	for _, p := range opts.AppPatterns {
+		patterns = append(patterns, compileAppPattern(p, log))
+	}
+
+	space := currentSpace(cli, log)
+
 	drains, err := df.Drains(space.Guid)
 	if err != nil {
 		log.Fatalf("%s", err)
 	}
 
-	if !containsDrain(drains, drainName) {
+	d, ok := findDrain(drains, drainName)
+	if !ok {
 		log.Fatalf("%s is not a valid drain.", drainName)
 	}
 
-	_, err = cli.CliCommand("bind-service", appName, drainName)
-	if err != nil {
-		log.Fatalf("%s", err)
+	if hasSelectors {
+		matched := selectApps(cli, al, space.Guid, patterns, opts.Labels, log)
+		if len(matched) == 0 && len(appNames) == 0 {
+			log.Fatalf("No apps matched the given selectors.")
+		}
+		appNames = append(appNames, matched...)
+	}
+
+	var failed, total int
+	seen := make(map[string]bool)
+	for _, appName := range appNames {
+		if seen[appName] {
+			continue
+		}
+		seen[appName] = true
+		total++
+
+		if containsString(d.Apps, appName) {
+			log.Printf("%s is already bound to %s.", appName, drainName)
+			continue
+		}
+
+		_, err := cli.CliCommand("bind-service", appName, drainName)
+		if err != nil {
+			failed++
+			log.Printf("Failed to bind %s to %s: %s", appName, drainName, err)
+			continue
+		}
+		if isDryRun(cli) {
+			log.Printf("Would bind %s to %s.", appName, drainName)
+			continue
+		}
+		log.Printf("Bound %s to %s.", appName, drainName)
+	}
+
+	if failed > 0 {
+		log.Fatalf("Failed to bind %d of %d apps.", failed, total)
+	}
+}
+
+// selectApps returns the names of the apps in the space that match every
+// label selector and at least one of the patterns.
+func selectApps(
+	cli plugin.CliConnection,
+	al LabeledAppLister,
+	spaceGuid string,
+	patterns []appMatcher,
+	labels []string,
+	log Logger,
+) []string {
+	var candidates []string
+	if len(labels) > 0 {
+		// Cloud Controller ANDs comma separated requirements together.
+		apps, err := al.ListAppsByLabel(spaceGuid, strings.Join(labels, ","))
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		for _, app := range apps {
+			candidates = append(candidates, app.Name)
+		}
+	} else {
+		apps, err := cli.GetApps()
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		for _, app := range apps {
+			candidates = append(candidates, app.Name)
+		}
+	}
+
+	if len(patterns) == 0 {
+		return candidates
+	}
+
+	var matched []string
+	for _, name := range candidates {
+		for _, p := range patterns {
+			if p(name) {
+				matched = append(matched, name)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// appMatcher reports whether an app name matches an --app-pattern.
+type appMatcher func(name string) bool
+
+// compileAppPattern compiles an --app-pattern value. Patterns wrapped in
+// slashes are regular expressions, anything else is a glob.
+func compileAppPattern(pattern string, log Logger) appMatcher {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		r, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			log.Fatalf("Invalid app pattern %s: %s", pattern, err)
+		}
+		return r.MatchString
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		log.Fatalf("Invalid app pattern %s: %s", pattern, err)
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
 	}
 }
 
-func containsDrain(drains []drain.Drain, drainName string) bool {
+func findDrain(drains []drain.Drain, drainName string) (drain.Drain, bool) {
 	for _, d := range drains {
 		if d.Name == drainName {
-			return true
+			return d, true
 		}
 	}
-	return false
+	return drain.Drain{}, false
 }
@@ -3,8 +3,10 @@ package command_test
 import (
 	"errors"
 
+	"code.cloudfoundry.org/cf-drain-cli/internal/cloudcontroller"
 	"code.cloudfoundry.org/cf-drain-cli/internal/command"
 	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+	"code.cloudfoundry.org/cli/plugin/models"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -15,59 +17,203 @@ var _ = Describe("BindDrain", func() {
 		logger       *stubLogger
 		cli          *stubCliConnection
 		drainFetcher *stubDrainFetcher
+		appLister    *stubLabeledAppLister
 	)
 
 	BeforeEach(func() {
 		logger = &stubLogger{}
 		cli = newStubCliConnection()
 		cli.currentSpaceGuid = "space-guid"
+		cli.getAppsApps = []plugin_models.GetAppsModel{
+			{Name: "orders-api"},
+			{Name: "orders-worker"},
+			{Name: "billing-api"},
+		}
 		drainFetcher = newStubDrainFetcher()
 		drainFetcher.drains = []drain.Drain{
-			{Name: "drain-name"},
+			{Name: "drain-name", Apps: []string{"bound-app"}},
 		}
+		appLister = &stubLabeledAppLister{}
 	})
 
 	It("calls bind-service with the given app name and service", func() {
 		args := []string{"app-name", "drain-name"}
 
-		command.BindDrain(cli, drainFetcher, args, logger)
+		command.BindDrain(cli, drainFetcher, appLister, args, logger)
 
 		Expect(cli.cliCommandArgs).To(HaveLen(1))
 		Expect(cli.cliCommandArgs[0]).To(Equal([]string{
 			"bind-service", "app-name", "drain-name",
 		}))
+		Expect(logger.printfMessages).To(ConsistOf("Bound app-name to drain-name."))
 	})
 
-	It("fatally logs if it fails to bind to service", func() {
-		cli.bindServiceError = errors.New("unable to bind")
+	It("does not report apps as bound in a dry run", func() {
 		args := []string{"app-name", "drain-name"}
 
+		command.BindDrain(command.NewDryRunConnection(cli, logger), drainFetcher, appLister, args, logger)
+
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+		Expect(logger.printfMessages).To(Equal([]string{
+			"Would run: cf bind-service app-name drain-name",
+			"Would bind app-name to drain-name.",
+		}))
+	})
+
+	It("binds every given app name", func() {
+		args := []string{"app-1", "app-2", "app-1", "drain-name"}
+
+		command.BindDrain(cli, drainFetcher, appLister, args, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"bind-service", "app-1", "drain-name"},
+			{"bind-service", "app-2", "drain-name"},
+		}))
+	})
+
+	It("skips apps that are already bound", func() {
+		args := []string{"bound-app", "app-name", "drain-name"}
+
+		command.BindDrain(cli, drainFetcher, appLister, args, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"bind-service", "app-name", "drain-name"},
+		}))
+		Expect(logger.printfMessages).To(ContainElement("bound-app is already bound to drain-name."))
+	})
+
+	It("binds apps matching a glob app pattern", func() {
+		args := []string{"--app-pattern", "orders-*", "drain-name"}
+
+		command.BindDrain(cli, drainFetcher, appLister, args, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"bind-service", "orders-api", "drain-name"},
+			{"bind-service", "orders-worker", "drain-name"},
+		}))
+	})
+
+	It("binds apps matching a regex app pattern", func() {
+		args := []string{"--app-pattern", "/-api$/", "drain-name"}
+
+		command.BindDrain(cli, drainFetcher, appLister, args, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"bind-service", "orders-api", "drain-name"},
+			{"bind-service", "billing-api", "drain-name"},
+		}))
+	})
+
+	It("binds apps matching the label selectors", func() {
+		appLister.apps = []cloudcontroller.App{
+			{Name: "orders-api", Guid: "a"},
+			{Name: "billing-api", Guid: "b"},
+		}
+		args := []string{"--label", "team=orders", "--label", "tier=web", "drain-name"}
+
+		command.BindDrain(cli, drainFetcher, appLister, args, logger)
+
+		Expect(appLister.spaceGuid).To(Equal("space-guid"))
+		Expect(appLister.labelSelector).To(Equal("team=orders,tier=web"))
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"bind-service", "orders-api", "drain-name"},
+			{"bind-service", "billing-api", "drain-name"},
+		}))
+	})
+
+	It("binds apps matching both the label selectors and app patterns", func() {
+		appLister.apps = []cloudcontroller.App{
+			{Name: "orders-api", Guid: "a"},
+			{Name: "orders-worker", Guid: "b"},
+		}
+		args := []string{"--label", "team=orders", "--app-pattern", "*-worker", "extra-app", "drain-name"}
+
+		command.BindDrain(cli, drainFetcher, appLister, args, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"bind-service", "extra-app", "drain-name"},
+			{"bind-service", "orders-worker", "drain-name"},
+		}))
+	})
+
+	It("fatally logs if no apps match the selectors", func() {
+		args := []string{"--app-pattern", "unknown-*", "drain-name"}
+
+		Expect(func() {
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("No apps matched the given selectors."))
+	})
+
+	It("fatally logs if the app pattern is invalid", func() {
+		args := []string{"--app-pattern", "/[/", "drain-name"}
+
+		Expect(func() {
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(HavePrefix("Invalid app pattern /[/:"))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("fatally logs if it fails to list apps by label", func() {
+		appLister.err = errors.New("failed to list apps")
+		args := []string{"--label", "team=orders", "drain-name"}
+
+		Expect(func() {
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("failed to list apps"))
+	})
+
+	It("fatally logs if it fails to list apps", func() {
+		cli.getAppsError = errors.New("failed to list apps")
+		args := []string{"--app-pattern", "orders-*", "drain-name"}
+
+		Expect(func() {
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("failed to list apps"))
+	})
+
+	It("keeps binding apps when one fails and reports a summary", func() {
+		cli.bindServiceErrors["app-1"] = errors.New("unable to bind")
+		args := []string{"app-1", "app-2", "drain-name"}
+
 		Expect(func() {
-			command.BindDrain(cli, drainFetcher, args, logger)
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
 		}).To(Panic())
-		Expect(logger.fatalfMessage).To(Equal("unable to bind"))
+
+		Expect(cli.cliCommandArgs).To(HaveLen(2))
+		Expect(logger.printfMessages).To(Equal([]string{
+			"Failed to bind app-1 to drain-name: unable to bind",
+			"Bound app-2 to drain-name.",
+		}))
+		Expect(logger.fatalfMessage).To(Equal("Failed to bind 1 of 2 apps."))
 	})
 
-	It("expects to receive 2 arguments", func() {
-		args := []string{"app-name", "drain-name", "extra"}
+	It("expects to receive at least 2 arguments", func() {
+		args := []string{"app-name"}
 
 		Expect(func() {
-			command.BindDrain(cli, drainFetcher, args, logger)
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
 		}).To(Panic())
-		Expect(logger.fatalfMessage).To(Equal("Invalid arguments, expected 2, got 3."))
+		Expect(logger.fatalfMessage).To(Equal("Invalid arguments, expected at least 2, got 1."))
+	})
+
+	It("expects to receive at least 1 argument with selectors", func() {
+		args := []string{"--app-pattern", "orders-*"}
 
-		args = []string{"app-name"}
 		Expect(func() {
-			command.BindDrain(cli, drainFetcher, args, logger)
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
 		}).To(Panic())
-		Expect(logger.fatalfMessage).To(Equal("Invalid arguments, expected 2, got 1."))
+		Expect(logger.fatalfMessage).To(Equal("Invalid arguments, expected at least 1, got 0."))
 	})
 
 	It("fatally logs if the drain does not exist", func() {
 		args := []string{"app-name", "unknown-drain-name"}
 
 		Expect(func() {
-			command.BindDrain(cli, drainFetcher, args, logger)
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
 		}).To(Panic())
 		Expect(logger.fatalfMessage).To(Equal("unknown-drain-name is not a valid drain."))
 	})
@@ -77,7 +223,7 @@ var _ = Describe("BindDrain", func() {
 		drainFetcher.err = errors.New("Failed to fetch drains.")
 
 		Expect(func() {
-			command.BindDrain(cli, drainFetcher, args, logger)
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
 		}).To(Panic())
 		Expect(logger.fatalfMessage).To(Equal("Failed to fetch drains."))
 	})
@@ -87,8 +233,22 @@ var _ = Describe("BindDrain", func() {
 		cli.currentSpaceError = errors.New("Failed to get space.")
 
 		Expect(func() {
-			command.BindDrain(cli, drainFetcher, args, logger)
+			command.BindDrain(cli, drainFetcher, appLister, args, logger)
 		}).To(Panic())
 		Expect(logger.fatalfMessage).To(Equal("Failed to get space."))
 	})
 })
+
+type stubLabeledAppLister struct {
+	spaceGuid     string
+	labelSelector string
+
+	apps []cloudcontroller.App
+	err  error
+}
+
+func (s *stubLabeledAppLister) ListAppsByLabel(spaceGuid, labelSelector string) ([]cloudcontroller.App, error) {
+	s.spaceGuid = spaceGuid
+	s.labelSelector = labelSelector
+	return s.apps, s.err
+}
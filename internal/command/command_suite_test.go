@@ -2,6 +2,7 @@ package command_test
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"testing"
 
@@ -46,18 +47,26 @@ type stubCliConnection struct {
 	createUserError    error
 	createServiceError error
 	bindServiceError   error
+	bindServiceErrors  map[string]error
 	unbindServiceError error
 	deleteServiceError error
 	pushAppError       error
 	startAppError      error
 	deleteAppError     error
+	updateServiceError error
+	restageAppError    error
 
 	currentSpaceName  string
 	currentSpaceGuid  string
 	currentSpaceError error
 	currentOrgName    string
+	currentOrgGuid    string
 	currentOrgError   error
 
+	orgs         map[string][]plugin_models.GetOrg_Space
+	getOrgsError error
+	getOrgError  error
+
 	apiEndpoint      string
 	apiEndpointError error
 
@@ -68,6 +77,7 @@ func newStubCliConnection() *stubCliConnection {
 	return &stubCliConnection{
 		cliCommandWithoutTerminalOutputResponse: make(map[string]string),
 		setEnvErrors:                            make(map[string]error),
+		bindServiceErrors:                       make(map[string]error),
 	}
 }
 
@@ -99,10 +109,34 @@ func (s *stubCliConnection) GetCurrentOrg() (plugin_models.Organization, error)
 	return plugin_models.Organization{
 		OrganizationFields: plugin_models.OrganizationFields{
 			Name: s.currentOrgName,
+			Guid: s.currentOrgGuid,
 		},
 	}, s.currentOrgError
 }
 
+func (s *stubCliConnection) GetOrgs() ([]plugin_models.GetOrgs_Model, error) {
+	var orgs []plugin_models.GetOrgs_Model
+	for name := range s.orgs {
+		orgs = append(orgs, plugin_models.GetOrgs_Model{
+			Name: name,
+			Guid: name + "-guid",
+		})
+	}
+	sort.Slice(orgs, func(i, j int) bool {
+		return orgs[i].Name < orgs[j].Name
+	})
+
+	return orgs, s.getOrgsError
+}
+
+func (s *stubCliConnection) GetOrg(name string) (plugin_models.GetOrg_Model, error) {
+	return plugin_models.GetOrg_Model{
+		Name:   name,
+		Guid:   name + "-guid",
+		Spaces: s.orgs[name],
+	}, s.getOrgError
+}
+
 func (s *stubCliConnection) GetServices() ([]plugin_models.GetServices_Model, error) {
 	resp := []plugin_models.GetServices_Model{
 		{
@@ -146,6 +180,12 @@ func (s *stubCliConnection) CliCommandWithoutTerminalOutput(args ...string) ([]s
 	switch args[0] {
 	case "set-env":
 		err = s.setEnvErrors[args[2]]
+	case "create-user-provided-service", "create-service":
+		err = s.createServiceError
+	case "bind-service":
+		err = s.bindServiceError
+	case "delete-service":
+		err = s.deleteServiceError
 	}
 
 	return strings.Split(output, "\n"), err
@@ -160,6 +200,9 @@ func (s *stubCliConnection) CliCommand(args ...string) ([]string, error) {
 		err = s.createServiceError
 	case "bind-service":
 		err = s.bindServiceError
+		if e, ok := s.bindServiceErrors[args[1]]; ok {
+			err = e
+		}
 	case "unbind-service":
 		err = s.unbindServiceError
 	case "delete-service":
@@ -170,6 +213,10 @@ func (s *stubCliConnection) CliCommand(args ...string) ([]string, error) {
 		err = s.startAppError
 	case "delete":
 		err = s.deleteAppError
+	case "update-user-provided-service":
+		err = s.updateServiceError
+	case "restage":
+		err = s.restageAppError
 	}
 
 	s.cliCommandArgs = append(s.cliCommandArgs, args)
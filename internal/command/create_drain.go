@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/url"
 
+	"code.cloudfoundry.org/cf-drain-cli/internal/drainurl"
 	"code.cloudfoundry.org/cli/plugin"
 	flags "github.com/jessevdk/go-flags"
 	uuid "github.com/nu7hatch/gouuid"
@@ -18,10 +19,11 @@ type Logger interface {
 }
 
 type createDrainOpts struct {
-	AppName   string
-	DrainName string `long:"drain-name"`
-	DrainType string `long:"type"`
-	DrainURL  string
+	AppName        string
+	DrainName      string `long:"drain-name"`
+	DrainType      string `long:"type"`
+	SkipValidation bool   `long:"skip-validation"`
+	DrainURL       string
 }
 
 func (f *createDrainOpts) drainName() string {
@@ -63,13 +65,11 @@ func CreateDrain(
 	}
 
 	if opts.DrainType != "" {
-		if !validDrainType(opts.DrainType) {
-			log.Fatalf("Invalid type: %s", opts.DrainType)
-		}
+		setDrainType(u, opts.DrainType, log)
+	}
 
-		qValues := u.Query()
-		qValues.Set("drain-type", opts.DrainType)
-		u.RawQuery = qValues.Encode()
+	if !opts.SkipValidation {
+		validateDrainURL(u.String(), log)
 	}
 
 	createAndBindService(cli, u, opts.AppName, opts.drainName(), log)
@@ -113,6 +113,26 @@ func sourceID(cli plugin.CliConnection, appName string) (string, error) {
 	return app.Guid, nil
 }
 
+// setDrainType sets the drain-type query parameter of the drain URL. It
+// fatally logs for unknown drain types.
+func setDrainType(u *url.URL, drainType string, log Logger) {
+	if !validDrainType(drainType) {
+		log.Fatalf("Invalid type: %s", drainType)
+	}
+
+	qValues := u.Query()
+	qValues.Set("drain-type", drainType)
+	u.RawQuery = qValues.Encode()
+}
+
+// validateDrainURL fatally logs if the drain URL is not one Loggregator can
+// write to.
+func validateDrainURL(drainURL string, log Logger, opts ...drainurl.Option) {
+	if err := drainurl.Validate(drainURL, opts...); err != nil {
+		log.Fatalf("Invalid syslog drain URL: %s", err)
+	}
+}
+
 func validDrainType(drainType string) bool {
 	switch drainType {
 	case "logs", "metrics", "all":
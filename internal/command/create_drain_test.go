@@ -20,7 +20,7 @@ var _ = Describe("CreateDrain", func() {
 	})
 
 	It("creates and binds to a user provided service", func() {
-		args := []string{"app-name", "syslog://a.com?a=b"}
+		args := []string{"app-name", "syslog://a.com:514?omit-metadata=true"}
 
 		command.CreateDrain(cli, args, logger)
 
@@ -29,7 +29,7 @@ var _ = Describe("CreateDrain", func() {
 			"create-user-provided-service",
 			MatchRegexp("cf-drain-[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}"),
 			"-l",
-			"syslog://a.com?a=b",
+			"syslog://a.com:514?omit-metadata=true",
 		))
 
 		Expect(cli.cliCommandArgs[1]).To(ConsistOf(
@@ -43,7 +43,7 @@ var _ = Describe("CreateDrain", func() {
 		It("creates and binds to a user provided service with the given name", func() {
 			args := []string{
 				"app-name",
-				"syslog://a.com?a=b",
+				"syslog://a.com:514?omit-metadata=true",
 				"--drain-name", "my-drain",
 			}
 
@@ -53,7 +53,7 @@ var _ = Describe("CreateDrain", func() {
 			Expect(cli.cliCommandArgs[0]).To(ConsistOf(
 				"create-user-provided-service",
 				"my-drain",
-				"-l", "syslog://a.com?a=b",
+				"-l", "syslog://a.com:514?omit-metadata=true",
 			))
 
 			Expect(cli.cliCommandArgs[1]).To(ConsistOf(
@@ -64,7 +64,7 @@ var _ = Describe("CreateDrain", func() {
 		})
 
 		It("creates random drain name if --drain-name flag is not given", func() {
-			args := []string{"app-name", "syslog://a.com?a=b"}
+			args := []string{"app-name", "syslog://a.com:514?omit-metadata=true"}
 
 			command.CreateDrain(cli, args, logger)
 
@@ -74,7 +74,7 @@ var _ = Describe("CreateDrain", func() {
 			Expect(cli.cliCommandArgs[0]).To(ConsistOf(
 				"create-user-provided-service",
 				drainName,
-				"-l", "syslog://a.com?a=b",
+				"-l", "syslog://a.com:514?omit-metadata=true",
 			))
 
 			Expect(cli.cliCommandArgs[1]).To(ConsistOf(
@@ -87,7 +87,7 @@ var _ = Describe("CreateDrain", func() {
 
 	Describe("type flag", func() {
 		It("adds the drain type to the syslog URL for metrics", func() {
-			args := []string{"--type", "metrics", "app-name", "syslog://a.com"}
+			args := []string{"--type", "metrics", "app-name", "syslog://a.com:514"}
 
 			command.CreateDrain(cli, args, logger)
 
@@ -95,12 +95,12 @@ var _ = Describe("CreateDrain", func() {
 			Expect(cli.cliCommandArgs[0]).To(ConsistOf(
 				"create-user-provided-service",
 				MatchRegexp("cf-drain-[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}"),
-				"-l", "syslog://a.com?drain-type=metrics",
+				"-l", "syslog://a.com:514?drain-type=metrics",
 			))
 		})
 
 		It("adds the drain type to the syslog URL for logs", func() {
-			args := []string{"--type", "logs", "app-name", "syslog://a.com"}
+			args := []string{"--type", "logs", "app-name", "syslog://a.com:514"}
 
 			command.CreateDrain(cli, args, logger)
 
@@ -108,12 +108,12 @@ var _ = Describe("CreateDrain", func() {
 			Expect(cli.cliCommandArgs[0]).To(ConsistOf(
 				"create-user-provided-service",
 				MatchRegexp("cf-drain-[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}"),
-				"-l", "syslog://a.com?drain-type=logs",
+				"-l", "syslog://a.com:514?drain-type=logs",
 			))
 		})
 
 		It("adds the drain type to the syslog URL for all", func() {
-			args := []string{"--type", "all", "app-name", "syslog://a.com"}
+			args := []string{"--type", "all", "app-name", "syslog://a.com:514"}
 
 			command.CreateDrain(cli, args, logger)
 
@@ -121,12 +121,12 @@ var _ = Describe("CreateDrain", func() {
 			Expect(cli.cliCommandArgs[0]).To(ConsistOf(
 				"create-user-provided-service",
 				MatchRegexp("cf-drain-[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}"),
-				"-l", "syslog://a.com?drain-type=all",
+				"-l", "syslog://a.com:514?drain-type=all",
 			))
 		})
 
 		It("fatally logs for unknown drain types", func() {
-			args := []string{"--type", "garbage", "app-name", "syslog://a.com"}
+			args := []string{"--type", "garbage", "app-name", "syslog://a.com:514"}
 
 			Expect(func() {
 				command.CreateDrain(cli, args, logger)
@@ -144,6 +144,38 @@ var _ = Describe("CreateDrain", func() {
 		Expect(logger.fatalfMessage).To(Equal("Invalid syslog drain URL: parse \"://://blablabla\": missing protocol scheme"))
 	})
 
+	It("fatally logs if the drain URL fails validation", func() {
+		args := []string{"app-name", "sylog://a.com:514"}
+
+		Expect(func() {
+			command.CreateDrain(cli, args, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal(`Invalid syslog drain URL: unsupported scheme "sylog", expected one of syslog, syslog-tls, https`))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("fatally logs if the drain URL is missing a port", func() {
+		args := []string{"app-name", "syslog://a.com"}
+
+		Expect(func() {
+			command.CreateDrain(cli, args, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Invalid syslog drain URL: missing port, syslog drains require a port"))
+	})
+
+	It("skips validation with --skip-validation", func() {
+		args := []string{"app-name", "sylog://a.com?a=b", "--skip-validation"}
+
+		command.CreateDrain(cli, args, logger)
+
+		Expect(cli.cliCommandArgs).To(HaveLen(2))
+		Expect(cli.cliCommandArgs[0]).To(ConsistOf(
+			"create-user-provided-service",
+			MatchRegexp("cf-drain-[0-9a-f]{8}-([0-9a-f]{4}-){3}[0-9a-f]{12}"),
+			"-l", "sylog://a.com?a=b",
+		))
+	})
+
 	It("fatally logs if the incorrect number of arguments are given", func() {
 		Expect(func() {
 			command.CreateDrain(nil, []string{}, logger)
@@ -163,7 +195,7 @@ var _ = Describe("CreateDrain", func() {
 		cli.getAppError = errors.New("not an app")
 
 		Expect(func() {
-			command.CreateDrain(cli, []string{"not-an-app", "syslog://a.com"}, logger)
+			command.CreateDrain(cli, []string{"not-an-app", "syslog://a.com:514"}, logger)
 		}).To(Panic())
 
 		Expect(logger.fatalfMessage).To(Equal("not an app"))
@@ -174,7 +206,7 @@ var _ = Describe("CreateDrain", func() {
 		cli.createServiceError = errors.New("failed to create")
 
 		Expect(func() {
-			command.CreateDrain(cli, []string{"app-name", "syslog://a.com"}, logger)
+			command.CreateDrain(cli, []string{"app-name", "syslog://a.com:514"}, logger)
 		}).To(Panic())
 
 		Expect(logger.fatalfMessage).To(Equal("failed to create"))
@@ -184,7 +216,7 @@ var _ = Describe("CreateDrain", func() {
 		cli.bindServiceError = errors.New("failed to bind")
 
 		Expect(func() {
-			command.CreateDrain(cli, []string{"app-name", "syslog://a.com"}, logger)
+			command.CreateDrain(cli, []string{"app-name", "syslog://a.com:514"}, logger)
 		}).To(Panic())
 
 		Expect(logger.fatalfMessage).To(Equal("failed to bind"))
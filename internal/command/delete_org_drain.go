@@ -0,0 +1,110 @@
+package command
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+	"code.cloudfoundry.org/cli/plugin"
+	flags "github.com/jessevdk/go-flags"
+)
+
+// DrainRemover unbinds apps from drains and deletes drains by GUID, so
+// drains outside of the targeted space can be removed.
+type DrainRemover interface {
+	UnbindDrain(appGuid, serviceInstanceGuid string) error
+	DeleteDrain(serviceInstanceGuid string) error
+}
+
+// DeleteOrgDrain deletes an org drain app and the drains it created in
+// every space of the current org.
+func DeleteOrgDrain(cli plugin.CliConnection, args []string, log Logger, in io.Reader, df DrainFetcher, dr DrainRemover) {
+	opts := deleteDrainOpts{}
+	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	args, err := parser.ParseArgs(args)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if len(args) != 1 {
+		log.Fatalf("Invalid arguments, expected 1, got %d.", len(args))
+	}
+
+	drainName := args[0]
+
+	if !opts.Force {
+		log.Print("Are you sure you want to delete the org drain and the drains it created in every space? [y/N] ")
+
+		reader := bufio.NewReader(in)
+		confirm, err := reader.ReadString('\n')
+		if err != nil {
+			log.Fatalf("Failed to read user input: %s", err)
+		}
+
+		if strings.ToLower(strings.TrimSpace(confirm)) != "y" {
+			log.Printf("Delete cancelled")
+			return
+		}
+	}
+
+	app, err := cli.GetApp(drainName)
+	if err != nil {
+		log.Fatalf("Failed to get app: %s %s", drainName, err)
+	}
+
+	org, err := cli.GetCurrentOrg()
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	// The app is deleted first so it does not recreate the drains while
+	// they are removed.
+	_, err = cli.CliCommand("delete", drainName, "-f")
+	if err != nil {
+		log.Fatalf("Failed to delete org-drain: %s", err)
+	}
+
+	tag := drain.SpaceDrainTag(app.Guid)
+	for _, s := range orgSpaces(cli, org.Name, log) {
+		drains, err := df.Drains(s.guid)
+		if err != nil {
+			log.Fatalf("Failed to fetch drains in space %s: %s", s.name, err)
+		}
+
+		for _, d := range drains {
+			if !containsString(d.Tags, tag) {
+				continue
+			}
+
+			removeDrain(d, s.name, dr, log)
+			if !isDryRun(cli) {
+				log.Printf("Deleted drain %s in space %s.", d.Name, s.name)
+			}
+		}
+	}
+
+	serviceName := credentialServiceName(drainName)
+	if _, err := cli.GetService(serviceName); err != nil {
+		return
+	}
+
+	_, err = cli.CliCommandWithoutTerminalOutput("delete-service", serviceName, "-f")
+	if err != nil {
+		log.Fatalf("Failed to delete service %s: %s", serviceName, err)
+	}
+}
+
+func removeDrain(d drain.Drain, spaceName string, dr DrainRemover, log Logger) {
+	for _, appGuid := range d.AppGuids {
+		err := dr.UnbindDrain(appGuid, d.Guid)
+		if err != nil {
+			log.Fatalf("Failed to unbind app %s from drain %s in space %s: %s", appGuid, d.Name, spaceName, err)
+		}
+	}
+
+	err := dr.DeleteDrain(d.Guid)
+	if err != nil {
+		log.Fatalf("Failed to delete drain %s in space %s: %s", d.Name, spaceName, err)
+	}
+}
@@ -0,0 +1,151 @@
+package command_test
+
+import (
+	"bytes"
+	"errors"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/command"
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+	plugin_models "code.cloudfoundry.org/cli/plugin/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeleteOrgDrain", func() {
+	var (
+		cli          *stubCliConnection
+		logger       *stubLogger
+		reader       *bytes.Buffer
+		drainFetcher *stubDrainFetcher
+		remover      *spyDrainRemover
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		reader = bytes.NewBuffer(nil)
+		remover = &spyDrainRemover{}
+
+		cli = newStubCliConnection()
+		cli.getAppGuid = "org-drain-guid"
+		cli.currentOrgName = "my-org"
+		cli.orgs = map[string][]plugin_models.GetOrg_Space{
+			"my-org": {
+				{Name: "space-1", Guid: "space-1-guid"},
+				{Name: "space-2", Guid: "space-2-guid"},
+			},
+		}
+
+		drainFetcher = newStubDrainFetcher()
+		drainFetcher.spaceDrains["space-1-guid"] = []drain.Drain{
+			{
+				Name:     "my-drain",
+				Guid:     "drain-1-guid",
+				AppGuids: []string{"app-1-guid", "app-2-guid"},
+				Tags:     []string{"space-drain:org-drain-guid"},
+			},
+			{
+				Name: "other-drain",
+				Guid: "other-drain-guid",
+			},
+		}
+		drainFetcher.spaceDrains["space-2-guid"] = []drain.Drain{
+			{
+				Name: "my-drain",
+				Guid: "drain-2-guid",
+				Tags: []string{"space-drain:org-drain-guid"},
+			},
+			{
+				Name:     "other-org-drain",
+				Guid:     "other-org-drain-guid",
+				AppGuids: []string{"app-3-guid"},
+				Tags:     []string{"space-drain:other-guid"},
+			},
+		}
+	})
+
+	It("deletes the org drain app and the drains it created in every space", func() {
+		reader.WriteString("y\n")
+		command.DeleteOrgDrain(cli, []string{"my-drain"}, logger, reader, drainFetcher, remover)
+
+		Expect(cli.getAppName).To(Equal("my-drain"))
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"delete", "my-drain", "-f"},
+		}))
+		Expect(drainFetcher.spaceGuids).To(Equal([]string{"space-1-guid", "space-2-guid"}))
+		Expect(remover.unbinds).To(Equal([][]string{
+			{"app-1-guid", "drain-1-guid"},
+			{"app-2-guid", "drain-1-guid"},
+		}))
+		Expect(remover.deletes).To(Equal([]string{"drain-1-guid", "drain-2-guid"}))
+		Expect(logger.printfMessages).To(ContainElement("Deleted drain my-drain in space space-2."))
+	})
+
+	It("deletes the service holding the refresh token", func() {
+		command.DeleteOrgDrain(cli, []string{"my-drain", "--force"}, logger, nil, drainFetcher, remover)
+
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(Equal([][]string{
+			{"delete-service", "my-drain-credentials", "-f"},
+		}))
+	})
+
+	It("does not delete anything if the user cancels", func() {
+		reader.WriteString("n\n")
+		command.DeleteOrgDrain(cli, []string{"my-drain"}, logger, reader, drainFetcher, remover)
+
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+		Expect(remover.deletes).To(BeEmpty())
+		Expect(logger.printfMessages).To(ContainElement("Delete cancelled"))
+	})
+
+	It("fatally logs if the app does not exist", func() {
+		cli.getAppError = errors.New("not found")
+
+		Expect(func() {
+			command.DeleteOrgDrain(cli, []string{"my-drain", "--force"}, logger, nil, drainFetcher, remover)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Failed to get app: my-drain not found"))
+		Expect(remover.deletes).To(BeEmpty())
+	})
+
+	It("fatally logs if a drain can not be deleted", func() {
+		remover.deleteErr = errors.New("some-error")
+
+		Expect(func() {
+			command.DeleteOrgDrain(cli, []string{"my-drain", "--force"}, logger, nil, drainFetcher, remover)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Failed to delete drain my-drain in space space-1: some-error"))
+	})
+
+	It("fatally logs if an app can not be unbound", func() {
+		remover.unbindErr = errors.New("some-error")
+
+		Expect(func() {
+			command.DeleteOrgDrain(cli, []string{"my-drain", "--force"}, logger, nil, drainFetcher, remover)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Failed to unbind app app-1-guid from drain my-drain in space space-1: some-error"))
+	})
+
+	It("fatally logs with invalid arguments", func() {
+		Expect(func() {
+			command.DeleteOrgDrain(cli, []string{}, logger, nil, drainFetcher, remover)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Invalid arguments, expected 1, got 0."))
+	})
+})
+
+type spyDrainRemover struct {
+	unbinds   [][]string
+	unbindErr error
+	deletes   []string
+	deleteErr error
+}
+
+func (s *spyDrainRemover) UnbindDrain(appGuid, serviceInstanceGuid string) error {
+	s.unbinds = append(s.unbinds, []string{appGuid, serviceInstanceGuid})
+	return s.unbindErr
+}
+
+func (s *spyDrainRemover) DeleteDrain(serviceInstanceGuid string) error {
+	s.deletes = append(s.deletes, serviceInstanceGuid)
+	return s.deleteErr
+}
@@ -52,4 +52,16 @@ func DeleteSpaceDrain(cli plugin.CliConnection, args []string, log Logger, in io
 	}
 
 	deleteDrain(cli, []string{drainName, "--force"}, log, nil, df)
+
+	// The service holding the refresh token of the drain app is of no use
+	// without the app.
+	serviceName := credentialServiceName(drainName)
+	if _, err := cli.GetService(serviceName); err != nil {
+		return
+	}
+
+	_, err = cli.CliCommandWithoutTerminalOutput("delete-service", serviceName, "-f")
+	if err != nil {
+		log.Fatalf("Failed to delete service %s: %s", serviceName, err)
+	}
 }
@@ -55,6 +55,30 @@ var _ = Describe("DeleteSpaceDrain", func() {
 		}))
 	})
 
+	It("deletes the service holding the refresh token", func() {
+		command.DeleteSpaceDrain(cli, []string{"my-drain", "--force"}, logger, nil, serviceDrainFetcher, deleteDrain.deleteDrain)
+
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(Equal([][]string{
+			{"delete-service", "my-drain-credentials", "-f"},
+		}))
+	})
+
+	It("does not delete a service if the drain has none", func() {
+		cli.getServiceError = errors.New("service not found")
+		command.DeleteSpaceDrain(cli, []string{"my-drain", "--force"}, logger, nil, serviceDrainFetcher, deleteDrain.deleteDrain)
+
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(BeEmpty())
+	})
+
+	It("fatals if deleting the service holding the refresh token fails", func() {
+		cli.deleteServiceError = errors.New("some-error")
+
+		Expect(func() {
+			command.DeleteSpaceDrain(cli, []string{"my-drain", "--force"}, logger, nil, serviceDrainFetcher, deleteDrain.deleteDrain)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Failed to delete service my-drain-credentials: some-error"))
+	})
+
 	It("deletes the drain", func() {
 		// Lower case
 		reader.WriteString("y\n")
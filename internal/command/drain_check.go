@@ -0,0 +1,73 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+type DrainChecker interface {
+	Check(drainURL string) drain.CheckResult
+}
+
+// DrainCheck connects to a drain and sends it a test message. The drain can
+// be given by name, in which case its URL is looked up in the current space,
+// or by URL.
+func DrainCheck(
+	cli plugin.CliConnection,
+	args []string,
+	log Logger,
+	tableWriter io.Writer,
+	df DrainFetcher,
+	c DrainChecker,
+) {
+	if len(args) != 1 {
+		log.Fatalf("Invalid arguments, expected 1, got %d.", len(args))
+	}
+
+	drainURL := args[0]
+	if !strings.Contains(drainURL, "://") {
+		drainURL = drainURLByName(cli, df, args[0], log)
+	}
+
+	log.Printf("Checking drain %s...", sanitizeDrainURL(drainURL))
+	r := c.Check(drainURL)
+
+	tw := tabwriter.NewWriter(tableWriter, 10, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "Step\tResult\tTime")
+	for _, s := range r.Steps {
+		result := "ok"
+		if s.Err != nil {
+			result = fmt.Sprintf("failed: %s", s.Err)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, result, s.Duration.Round(time.Microsecond))
+	}
+	tw.Flush()
+
+	if err := r.Err(); err != nil {
+		log.Fatalf("Drain check failed: %s", err)
+	}
+}
+
+func drainURLByName(cli plugin.CliConnection, df DrainFetcher, drainName string, log Logger) string {
+	space := currentSpace(cli, log)
+
+	drains, err := df.Drains(space.Guid)
+	if err != nil {
+		log.Fatalf("Failed to fetch drains: %s", err)
+	}
+
+	for _, d := range drains {
+		if d.Name == drainName {
+			return d.DrainURL
+		}
+	}
+
+	log.Fatalf("%s is not a valid drain.", drainName)
+	return ""
+}
@@ -0,0 +1,186 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// drainManifest is the declarative description of the drains in a space
+// that is read by `drains apply` and written by `drains export`.
+type drainManifest struct {
+	Drains      []manifestDrain      `yaml:"drains"`
+	SpaceDrains []manifestSpaceDrain `yaml:"space_drains,omitempty"`
+}
+
+// manifestDrain is a single drain in a drain manifest. The apps bound to the
+// drain are the apps listed by name plus the apps matching the selectors.
+type manifestDrain struct {
+	Name        string   `yaml:"name"`
+	URL         string   `yaml:"url"`
+	Type        string   `yaml:"type,omitempty"`
+	Apps        []string `yaml:"apps,omitempty"`
+	AppPatterns []string `yaml:"app_patterns,omitempty"`
+	Labels      []string `yaml:"labels,omitempty"`
+}
+
+// manifestSpaceDrain is a space drain app, see `drain-space`. The drain it
+// creates and binds is managed by the app and not listed under drains.
+type manifestSpaceDrain struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	Type string `yaml:"type,omitempty"`
+}
+
+func (d manifestDrain) hasSelectors() bool {
+	return len(d.AppPatterns) > 0 || len(d.Labels) > 0
+}
+
+var envPlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// loadDrainManifest reads and validates the drain manifest at the given
+// path. ${VAR} placeholders in drain URLs are replaced with the value of the
+// environment variable so credentials do not need to be committed.
+func loadDrainManifest(path string) (drainManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return drainManifest{}, err
+	}
+
+	var m drainManifest
+	err = yaml.UnmarshalStrict(data, &m)
+	if err != nil {
+		return drainManifest{}, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i, d := range m.Drains {
+		if d.Name == "" {
+			return drainManifest{}, fmt.Errorf("drain %d is missing a name", i+1)
+		}
+		if names[d.Name] {
+			return drainManifest{}, fmt.Errorf("drain %s is listed more than once", d.Name)
+		}
+		names[d.Name] = true
+
+		if d.URL == "" {
+			return drainManifest{}, fmt.Errorf("drain %s is missing a url", d.Name)
+		}
+
+		drainURL, err := expandEnv(d.URL)
+		if err != nil {
+			return drainManifest{}, fmt.Errorf("drain %s: %s", d.Name, err)
+		}
+
+		if d.Type != "" {
+			if !validDrainType(d.Type) {
+				return drainManifest{}, fmt.Errorf("drain %s has an invalid type: %s", d.Name, d.Type)
+			}
+
+			u, err := url.Parse(drainURL)
+			if err != nil {
+				return drainManifest{}, fmt.Errorf("drain %s has an invalid url: %s", d.Name, err)
+			}
+			q := u.Query()
+			q.Set("drain-type", d.Type)
+			u.RawQuery = q.Encode()
+			drainURL = u.String()
+		}
+
+		m.Drains[i].URL = drainURL
+	}
+
+	for i, d := range m.SpaceDrains {
+		if d.Name == "" {
+			return drainManifest{}, fmt.Errorf("space drain %d is missing a name", i+1)
+		}
+		if names[d.Name] {
+			return drainManifest{}, fmt.Errorf("drain %s is listed more than once", d.Name)
+		}
+		names[d.Name] = true
+
+		if d.URL == "" {
+			return drainManifest{}, fmt.Errorf("space drain %s is missing a url", d.Name)
+		}
+
+		drainURL, err := expandEnv(d.URL)
+		if err != nil {
+			return drainManifest{}, fmt.Errorf("space drain %s: %s", d.Name, err)
+		}
+		m.SpaceDrains[i].URL = drainURL
+
+		if d.Type == "" {
+			m.SpaceDrains[i].Type = "all"
+		} else if !validDrainType(d.Type) {
+			return drainManifest{}, fmt.Errorf("space drain %s has an invalid type: %s", d.Name, d.Type)
+		}
+	}
+
+	return m, nil
+}
+
+// expandEnv replaces the ${VAR} placeholders in the drain URL. Values are
+// escaped for the part of the URL they are in, so credentials may contain
+// characters like @, / or &.
+func expandEnv(s string) (string, error) {
+	var (
+		expanded string
+		missing  string
+		last     int
+	)
+	for _, m := range envPlaceholder.FindAllStringSubmatchIndex(s, -1) {
+		name := s[m[2]:m[3]]
+		v, ok := os.LookupEnv(name)
+		if !ok && missing == "" {
+			missing = name
+		}
+
+		expanded += s[last:m[0]] + urlEscaper(s, m[0])(v)
+		last = m[1]
+	}
+
+	if missing != "" {
+		return "", fmt.Errorf("environment variable %s is not set", missing)
+	}
+
+	return expanded + s[last:], nil
+}
+
+// urlEscaper returns the escape function for a value inserted at index i of
+// the raw URL s. Values in the host are not escaped.
+func urlEscaper(s string, i int) func(string) string {
+	var pathStart int
+	if n := strings.Index(s, "://"); n >= 0 {
+		hostStart := n + len("://")
+		pathStart = len(s)
+		if end := strings.IndexAny(s[hostStart:], "/?#"); end >= 0 {
+			pathStart = hostStart + end
+		}
+
+		if i < pathStart {
+			at := strings.LastIndex(s[hostStart:pathStart], "@")
+			if at >= 0 && i < hostStart+at {
+				return func(v string) string {
+					return url.User(v).String()
+				}
+			}
+			return func(v string) string { return v }
+		}
+	}
+
+	query := strings.Index(s[pathStart:], "?")
+	fragment := strings.Index(s[pathStart:], "#")
+	switch {
+	case fragment >= 0 && i > pathStart+fragment:
+		return url.PathEscape
+	case query >= 0 && i > pathStart+query:
+		return url.QueryEscape
+	default:
+		return url.PathEscape
+	}
+}
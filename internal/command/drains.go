@@ -1,20 +1,33 @@
 package command
 
 import (
-	"fmt"
 	"io"
 	"net/url"
 	"strings"
-	"text/tabwriter"
 
 	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
 	"code.cloudfoundry.org/cli/plugin"
+	flags "github.com/jessevdk/go-flags"
 )
 
 type DrainFetcher interface {
 	Drains(spaceGUID string) ([]drain.Drain, error)
 }
 
+type drainsOpts struct {
+	Output    string `long:"output" short:"o"`
+	Org       string `long:"org"`
+	AllSpaces bool   `long:"all-spaces"`
+	Orphaned  bool   `long:"orphaned"`
+}
+
+// drainSpace is a space whose drains are listed.
+type drainSpace struct {
+	org  string
+	name string
+	guid string
+}
+
 func Drains(
 	cli plugin.CliConnection,
 	args []string,
@@ -22,42 +35,119 @@ func Drains(
 	tableWriter io.Writer,
 	fetchers ...DrainFetcher,
 ) {
+	opts := drainsOpts{
+		Output: "table",
+	}
+
+	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	args, err := parser.ParseArgs(args)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
 	if len(args) != 0 {
 		log.Fatalf("Invalid arguments, expected 0, got %d.", len(args))
 	}
 
-	space, err := cli.GetCurrentSpace()
+	if opts.Org != "" && opts.AllSpaces {
+		log.Fatalf("The --org and --all-spaces flags cannot be used together.")
+	}
+
+	write, ok := drainWriters[opts.Output]
+	if !ok {
+		log.Fatalf("Invalid output format: %s", opts.Output)
+	}
+
+	var spaces []drainSpace
+	switch {
+	case opts.Org != "":
+		spaces = orgSpaces(cli, opts.Org, log)
+	case opts.AllSpaces:
+		spaces = allSpaces(cli, log)
+	default:
+		spaces = []drainSpace{targetedSpace(cli, log)}
+	}
+
+	records, err := fetchDrainRecords(spaces, fetchers)
+	if err != nil {
+		log.Fatalf("Failed to fetch drains: %s", err)
+	}
+
+	if opts.Orphaned {
+		records = orphanedDrains(records)
+	}
+
+	multiSpace := opts.Org != "" || opts.AllSpaces
+	err = write(tableWriter, records, multiSpace)
+	if err != nil {
+		log.Fatalf("Failed to write drains: %s", err)
+	}
+}
+
+func targetedSpace(cli plugin.CliConnection, log Logger) drainSpace {
+	space := currentSpace(cli, log)
+
+	org, err := cli.GetCurrentOrg()
 	if err != nil {
 		log.Fatalf("%s", err)
 	}
 
-	var drains []drain.Drain
+	return drainSpace{
+		org:  org.Name,
+		name: space.Name,
+		guid: space.Guid,
+	}
+}
 
-	for _, f := range fetchers {
-		d, err := f.Drains(space.Guid)
-		if err != nil {
-			log.Fatalf("Failed to fetch drains: %s", err)
-		}
-		drains = append(drains, d...)
+func orgSpaces(cli plugin.CliConnection, orgName string, log Logger) []drainSpace {
+	org, err := cli.GetOrg(orgName)
+	if err != nil {
+		log.Fatalf("%s", err)
 	}
 
-	tw := tabwriter.NewWriter(tableWriter, 10, 2, 2, ' ', 0)
+	var spaces []drainSpace
+	for _, s := range org.Spaces {
+		spaces = append(spaces, drainSpace{
+			org:  org.Name,
+			name: s.Name,
+			guid: s.Guid,
+		})
+	}
+
+	return spaces
+}
+
+func allSpaces(cli plugin.CliConnection, log Logger) []drainSpace {
+	orgs, err := cli.GetOrgs()
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	var spaces []drainSpace
+	for _, o := range orgs {
+		spaces = append(spaces, orgSpaces(cli, o.Name, log)...)
+	}
+
+	return spaces
+}
 
-	// Header
-	fmt.Fprintln(tw, "App\tDrain\tType\tURL")
-	for _, d := range drains {
-		for _, app := range d.Apps {
-			drain := []string{
-				app,
-				d.Name,
-				strings.Title(d.Type),
-				sanitizeDrainURL(d.DrainURL),
+// fetchDrainRecords fetches the drains of every space, one space after the
+// other. The fetchers go through the CLI connection, whose calls read their
+// output from a buffer shared by all calls and so must not run
+// concurrently.
+func fetchDrainRecords(spaces []drainSpace, fetchers []DrainFetcher) ([]drainRecord, error) {
+	records := []drainRecord{}
+	for _, s := range spaces {
+		for _, f := range fetchers {
+			d, err := f.Drains(s.guid)
+			if err != nil {
+				return nil, err
 			}
-			fmt.Fprintln(tw, strings.Join(drain, "\t"))
+			records = append(records, newDrainRecords(s, d)...)
 		}
 	}
 
-	tw.Flush()
+	return records, nil
 }
 
 func sanitizeDrainURL(drainURL string) string {
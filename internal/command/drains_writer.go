@@ -0,0 +1,145 @@
+package command
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// drainRecord is the machine readable representation of a drain. Its fields
+// make up the schema of the json, yaml and csv outputs of the drains
+// command, so they should only ever be added to.
+type drainRecord struct {
+	Org      string   `json:"org" yaml:"org"`
+	Space    string   `json:"space" yaml:"space"`
+	Name     string   `json:"name" yaml:"name"`
+	Guid     string   `json:"guid" yaml:"guid"`
+	Type     string   `json:"type" yaml:"type"`
+	URL      string   `json:"url" yaml:"url"`
+	Apps     []string `json:"apps" yaml:"apps"`
+	AppGuids []string `json:"app_guids" yaml:"app_guids"`
+}
+
+func newDrainRecords(s drainSpace, drains []drain.Drain) []drainRecord {
+	records := []drainRecord{}
+	for _, d := range drains {
+		records = append(records, drainRecord{
+			Org:      s.org,
+			Space:    s.name,
+			Name:     d.Name,
+			Guid:     d.Guid,
+			Type:     d.Type,
+			URL:      sanitizeDrainURL(d.DrainURL),
+			Apps:     append([]string{}, d.Apps...),
+			AppGuids: append([]string{}, d.AppGuids...),
+		})
+	}
+
+	return records
+}
+
+// drainWriter writes drain records to w. multiSpace is set when the records
+// span more than the targeted space.
+type drainWriter func(w io.Writer, records []drainRecord, multiSpace bool) error
+
+var drainWriters = map[string]drainWriter{
+	"table": writeDrainsTable,
+	"json":  writeDrainsJSON,
+	"yaml":  writeDrainsYAML,
+	"csv":   writeDrainsCSV,
+}
+
+// orphanedDrains returns the records of drains without any bound apps.
+func orphanedDrains(records []drainRecord) []drainRecord {
+	orphaned := []drainRecord{}
+	for _, r := range records {
+		if len(r.Apps) == 0 {
+			orphaned = append(orphaned, r)
+		}
+	}
+
+	return orphaned
+}
+
+func writeDrainsTable(w io.Writer, records []drainRecord, multiSpace bool) error {
+	tw := tabwriter.NewWriter(w, 10, 2, 2, ' ', 0)
+
+	// Header
+	header := []string{"App", "Drain", "Type", "URL"}
+	if multiSpace {
+		header = append([]string{"Org", "Space"}, header...)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, r := range records {
+		apps := r.Apps
+		if len(apps) == 0 {
+			apps = []string{"<none>"}
+		}
+
+		for _, app := range apps {
+			drain := []string{
+				app,
+				r.Name,
+				strings.Title(r.Type),
+				r.URL,
+			}
+			if multiSpace {
+				drain = append([]string{r.Org, r.Space}, drain...)
+			}
+			fmt.Fprintln(tw, strings.Join(drain, "\t"))
+		}
+	}
+
+	return tw.Flush()
+}
+
+func writeDrainsJSON(w io.Writer, records []drainRecord, _ bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(struct {
+		Drains []drainRecord `json:"drains"`
+	}{records})
+}
+
+func writeDrainsYAML(w io.Writer, records []drainRecord, _ bool) error {
+	data, err := yaml.Marshal(struct {
+		Drains []drainRecord `yaml:"drains"`
+	}{records})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// writeDrainsCSV writes one row per drain. Apps and app GUIDs are joined
+// with semicolons and appear in the same order. New columns are appended so
+// positional consumers keep working.
+func writeDrainsCSV(w io.Writer, records []drainRecord, _ bool) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "guid", "type", "url", "apps", "app_guids", "org", "space"})
+	for _, r := range records {
+		cw.Write([]string{
+			r.Name,
+			r.Guid,
+			r.Type,
+			r.URL,
+			strings.Join(r.Apps, ";"),
+			strings.Join(r.AppGuids, ";"),
+			r.Org,
+			r.Space,
+		})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
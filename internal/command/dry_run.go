@@ -0,0 +1,123 @@
+package command
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// secretEnvs are env vars whose values are never printed in a dry run.
+var secretEnvs = map[string]bool{
+	"REFRESH_TOKEN": true,
+	"CLIENT_SECRET": true,
+}
+
+// serviceCommands are cf commands whose -p and -c JSON may hold credentials
+// and is never printed in a dry run.
+var serviceCommands = map[string]bool{
+	"create-user-provided-service": true,
+	"update-user-provided-service": true,
+	"create-service":               true,
+	"update-service":               true,
+}
+
+// DryRunConnection is a plugin.CliConnection that prints the cf commands
+// and Cloud Controller requests that would change anything instead of
+// running them. Read only calls are passed through to the wrapped
+// connection so commands can still resolve apps, services and spaces.
+type DryRunConnection struct {
+	plugin.CliConnection
+
+	log Logger
+}
+
+// NewDryRunConnection wraps the given connection so no mutating calls are
+// made.
+func NewDryRunConnection(cli plugin.CliConnection, log Logger) *DryRunConnection {
+	return &DryRunConnection{
+		CliConnection: cli,
+		log:           log,
+	}
+}
+
+// CliCommand prints the cf command and does not run it.
+func (c *DryRunConnection) CliCommand(args ...string) ([]string, error) {
+	c.log.Printf("Would run: cf %s", strings.Join(redactArgs(args), " "))
+	return nil, nil
+}
+
+// CliCommandWithoutTerminalOutput passes curl GET requests through and
+// prints every other command instead of running it.
+func (c *DryRunConnection) CliCommandWithoutTerminalOutput(args ...string) ([]string, error) {
+	if len(args) > 0 && args[0] == "curl" && curlMethod(args) == "GET" {
+		return c.CliConnection.CliCommandWithoutTerminalOutput(args...)
+	}
+
+	c.log.Printf("Would run: cf %s", strings.Join(redactArgs(args), " "))
+	return nil, nil
+}
+
+// isDryRun reports whether the connection only prints what it would do.
+// Commands use it to not report changes that were never made.
+func isDryRun(cli plugin.CliConnection) bool {
+	_, ok := cli.(*DryRunConnection)
+	return ok
+}
+
+// DryRunDownloader is a Downloader that does not download anything.
+type DryRunDownloader struct {
+	log Logger
+}
+
+// NewDryRunDownloader returns a Downloader for dry runs.
+func NewDryRunDownloader(log Logger) DryRunDownloader {
+	return DryRunDownloader{
+		log: log,
+	}
+}
+
+// Download prints the asset that would be downloaded and returns a
+// placeholder path for it.
+func (d DryRunDownloader) Download(assetName string) string {
+	d.log.Printf("Would download the latest %s release from github.", assetName)
+	return path.Join("<latest-release>", assetName)
+}
+
+func curlMethod(args []string) string {
+	for i, a := range args {
+		if a == "-X" && i+1 < len(args) {
+			return strings.ToUpper(args[i+1])
+		}
+	}
+	return "GET"
+}
+
+// redactArgs removes credentials from drain URLs, secret env var values
+// and service credentials so they are safe to print.
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, a := range args {
+		switch {
+		case i > 0 && secretEnvs[args[i-1]]:
+			redacted[i] = "<redacted>"
+		case i > 0 && serviceCommands[args[0]] && (args[i-1] == "-p" || args[i-1] == "-c"):
+			redacted[i] = "<redacted>"
+		case isURL(a):
+			redacted[i] = sanitizeDrainURL(a)
+		default:
+			redacted[i] = a
+		}
+	}
+
+	return redacted
+}
+
+func isURL(s string) bool {
+	if !strings.Contains(s, "://") {
+		return false
+	}
+	_, err := url.Parse(s)
+	return err == nil
+}
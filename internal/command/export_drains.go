@@ -0,0 +1,224 @@
+package command
+
+import (
+	"io"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/drainurl"
+	"code.cloudfoundry.org/cli/plugin"
+	flags "github.com/jessevdk/go-flags"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// AppEnvReader reads the environment variables of an app.
+type AppEnvReader interface {
+	EnvVars(appGUID string) (map[string]string, error)
+}
+
+type exportDrainsOpts struct {
+	File string `long:"file" short:"f"`
+}
+
+// spaceDrainApp is an app pushed by `drain-space`.
+type spaceDrainApp struct {
+	name      string
+	drainName string
+	drainURL  string
+	drainType string
+}
+
+// ExportDrains writes the drains in the targeted space as a drain manifest
+// that can be applied with `drains apply`. Credentials in drain URLs are
+// replaced with ${VAR} placeholders.
+func ExportDrains(
+	cli plugin.CliConnection,
+	df DrainFetcher,
+	er AppEnvReader,
+	args []string,
+	log Logger,
+	w io.Writer,
+) {
+	opts := exportDrainsOpts{}
+
+	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	args, err := parser.ParseArgs(args)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if len(args) != 0 {
+		log.Fatalf("Invalid arguments, expected 0, got %d.", len(args))
+	}
+
+	space := currentSpace(cli, log)
+	drains, err := df.Drains(space.Guid)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	spaceDrains := spaceDrainApps(cli, er, log)
+	managed := make(map[string]bool)
+	for _, sd := range spaceDrains {
+		managed[sd.drainName] = true
+	}
+	for _, d := range drains {
+		if d.CreatedBySpaceDrain() {
+			managed[d.Name] = true
+		}
+	}
+
+	m := drainManifest{
+		Drains: []manifestDrain{},
+	}
+	var vars []string
+
+	sort.Slice(drains, func(i, j int) bool {
+		return drains[i].Name < drains[j].Name
+	})
+	for _, d := range drains {
+		if managed[d.Name] {
+			continue
+		}
+
+		drainURL, v := placeholderDrainURL(d.Name, d.DrainURL)
+		vars = append(vars, v...)
+
+		md := manifestDrain{
+			Name: d.Name,
+			URL:  drainURL,
+			Apps: uniqueStrings(d.Apps),
+		}
+		if u, err := url.Parse(d.DrainURL); err == nil && u.Query().Get("drain-type") != "" {
+			md.Type = d.Type
+		}
+		m.Drains = append(m.Drains, md)
+	}
+
+	for _, sd := range spaceDrains {
+		drainURL, v := placeholderDrainURL(sd.name, sd.drainURL)
+		vars = append(vars, v...)
+
+		m.SpaceDrains = append(m.SpaceDrains, manifestSpaceDrain{
+			Name: sd.name,
+			URL:  drainURL,
+			Type: sd.drainType,
+		})
+	}
+
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		log.Fatalf("Failed to marshal drain manifest: %s", err)
+	}
+
+	if opts.File == "" {
+		w.Write(data)
+		return
+	}
+
+	err = ioutil.WriteFile(opts.File, data, 0600)
+	if err != nil {
+		log.Fatalf("Failed to write drain manifest: %s", err)
+	}
+
+	log.Printf("Exported %d drains and %d space drains to %s.", len(m.Drains), len(m.SpaceDrains), opts.File)
+	if len(vars) > 0 {
+		log.Printf("Set %s before applying the manifest.", strings.Join(uniqueStrings(vars), ", "))
+	}
+}
+
+// spaceDrainApps returns the space drain apps in the targeted space. They
+// are found by the DRAIN_SCOPE env var set by `drain-space`.
+func spaceDrainApps(cli plugin.CliConnection, er AppEnvReader, log Logger) []spaceDrainApp {
+	apps, err := cli.GetApps()
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	var spaceDrains []spaceDrainApp
+	for _, app := range apps {
+		envs, err := er.EnvVars(app.Guid)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		if envs["DRAIN_SCOPE"] != "space" {
+			continue
+		}
+
+		spaceDrains = append(spaceDrains, spaceDrainApp{
+			name:      app.Name,
+			drainName: envs["DRAIN_NAME"],
+			drainURL:  envs["DRAIN_URL"],
+			drainType: envs["DRAIN_TYPE"],
+		})
+	}
+
+	sort.Slice(spaceDrains, func(i, j int) bool {
+		return spaceDrains[i].name < spaceDrains[j].name
+	})
+
+	return spaceDrains
+}
+
+var nonEnvChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// placeholderDrainURL replaces the credentials in a drain URL with ${VAR}
+// placeholders named after the drain. The user info and any query parameter
+// Loggregator does not know about are treated as credentials. The
+// drain-type is dropped as it is written to the manifest separately. The
+// names of the placeholders are returned with the URL.
+func placeholderDrainURL(name, drainURL string) (string, []string) {
+	prefix := strings.Trim(nonEnvChars.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+
+	u, err := url.Parse(drainURL)
+	if err != nil {
+		v := prefix + "_URL"
+		return "${" + v + "}", []string{v}
+	}
+
+	var vars []string
+	placeholder := func(suffix string) string {
+		v := prefix + "_" + strings.Trim(nonEnvChars.ReplaceAllString(strings.ToUpper(suffix), "_"), "_")
+		vars = append(vars, v)
+		return "${" + v + "}"
+	}
+
+	s := u.Scheme + "://"
+	if u.User != nil {
+		s += placeholder("username")
+		if _, ok := u.User.Password(); ok {
+			s += ":" + placeholder("password")
+		}
+		s += "@"
+	}
+	s += u.Host + u.EscapedPath()
+
+	query := u.Query()
+	query.Del("drain-type")
+
+	var keys []string
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			if drainurl.KnownQueryParam(k) {
+				params = append(params, url.QueryEscape(k)+"="+url.QueryEscape(v))
+				continue
+			}
+			params = append(params, url.QueryEscape(k)+"="+placeholder(k))
+		}
+	}
+	if len(params) > 0 {
+		s += "?" + strings.Join(params, "&")
+	}
+
+	return s, vars
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	flags "github.com/jessevdk/go-flags"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// PushOrgDrain pushes a drain app that binds the apps in every space of the
+// current org, including spaces created later. The app itself runs in the
+// current space.
+func PushOrgDrain(
+	cli plugin.CliConnection,
+	args []string,
+	d Downloader,
+	f RefreshTokenFetcher,
+	log Logger,
+) {
+	opts := pushSpaceDrainOpts{
+		DrainType:       "all",
+		DrainName:       "org-drain",
+		CredentialStore: "user-provided",
+	}
+
+	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	args, err := parser.ParseArgs(args)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if len(args) != 1 {
+		log.Fatalf("Invalid arguments, expected 1, got %d.", len(args))
+	}
+
+	opts.DrainURL = args[0]
+	extraEnvs := drainAppEnvs(opts, log)
+
+	org, err := cli.GetCurrentOrg()
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	extraEnvs = append(extraEnvs, []string{"ORG_ID", org.Guid})
+
+	app, _ := cli.GetApp(opts.DrainName)
+	if app.Name == opts.DrainName {
+		log.Fatalf("A drain with that name already exists. Use --drain-name to create a drain with a different name.")
+	}
+
+	pushDrain(cli, opts.DrainName, "space_drain", "org", extraEnvs, opts, d, f, log)
+}
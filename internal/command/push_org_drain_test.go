@@ -0,0 +1,157 @@
+package command_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/command"
+)
+
+var _ = Describe("PushOrgDrain", func() {
+	var (
+		logger              *stubLogger
+		cli                 *stubCliConnection
+		downloader          *stubDownloader
+		refreshTokenFetcher *stubRefreshTokenFetcher
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		cli = newStubCliConnection()
+		cli.currentSpaceGuid = "space-guid"
+		cli.currentOrgGuid = "org-guid"
+		cli.getAppError = errors.New("app not found")
+		cli.getServiceError = errors.New("service not found")
+		cli.apiEndpoint = "https://api.something.com"
+		downloader = newStubDownloader()
+		downloader.path = "/downloaded/temp/dir/space_drain"
+
+		refreshTokenFetcher = newStubRefreshTokenFetcher()
+		refreshTokenFetcher.token = "some-refresh-token"
+	})
+
+	It("pushes the drain app with an org scope", func() {
+		command.PushOrgDrain(
+			cli,
+			[]string{
+				"https://some-drain",
+				"--path", "some-temp-dir",
+				"--type", "logs",
+			},
+			downloader,
+			refreshTokenFetcher,
+			logger,
+		)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{
+				"push", "org-drain",
+				"-p", "some-temp-dir",
+				"-b", "binary_buildpack",
+				"-c", "./space_drain",
+				"--no-start",
+			},
+			{"start", "org-drain"},
+		}))
+
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ConsistOf(
+			[]string{"set-env", "org-drain", "SPACE_ID", "space-guid"},
+			[]string{"set-env", "org-drain", "ORG_ID", "org-guid"},
+			[]string{"set-env", "org-drain", "DRAIN_NAME", "org-drain"},
+			[]string{"set-env", "org-drain", "DRAIN_URL", "https://some-drain"},
+			[]string{"set-env", "org-drain", "DRAIN_TYPE", "logs"},
+			[]string{"set-env", "org-drain", "API_ADDR", "https://api.something.com"},
+			[]string{"set-env", "org-drain", "UAA_ADDR", "https://uaa.something.com"},
+			[]string{"set-env", "org-drain", "CLIENT_ID", "cf"},
+			[]string{"create-user-provided-service", "org-drain-credentials", "-p", `{"refresh_token":"some-refresh-token"}`},
+			[]string{"bind-service", "org-drain", "org-drain-credentials"},
+			[]string{"set-env", "org-drain", "CREDENTIAL_STORE", "user-provided"},
+			[]string{"set-env", "org-drain", "CREDENTIAL_SERVICE_NAME", "org-drain-credentials"},
+			[]string{"set-env", "org-drain", "SKIP_CERT_VERIFY", "false"},
+			[]string{"set-env", "org-drain", "DRAIN_SCOPE", "org"},
+		))
+	})
+
+	It("sets the include and exclude filters of the org drain", func() {
+		command.PushOrgDrain(
+			cli,
+			[]string{
+				"https://some-drain",
+				"--path", "some-temp-dir",
+				"--drain-name", "some-drain",
+				"--include", "env=prod",
+				"--exclude-apps", "^smoke-",
+			},
+			downloader,
+			refreshTokenFetcher,
+			logger,
+		)
+
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+			[]string{"set-env", "some-drain", "INCLUDE_LABEL_SELECTOR", "env=prod"},
+		))
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+			[]string{"set-env", "some-drain", "EXCLUDE_APP_NAME_REGEX", "^smoke-"},
+		))
+	})
+
+	It("fatally logs if fetching the org fails", func() {
+		cli.currentOrgError = errors.New("some-error")
+
+		Expect(func() {
+			command.PushOrgDrain(
+				cli,
+				[]string{"https://some-drain", "--path", "some-temp-dir"},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("some-error"))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("fatally logs if a drain with the same name already exists", func() {
+		cli.getAppError = nil
+
+		Expect(func() {
+			command.PushOrgDrain(
+				cli,
+				[]string{"https://some-drain", "--path", "some-temp-dir"},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+		}).To(Panic())
+		Expect(cli.getAppName).To(Equal("org-drain"))
+		Expect(logger.fatalfMessage).To(Equal("A drain with that name already exists. Use --drain-name to create a drain with a different name."))
+	})
+
+	It("fatally logs if the drain URL fails validation", func() {
+		Expect(func() {
+			command.PushOrgDrain(
+				cli,
+				[]string{"ftp://some-drain", "--path", "some-temp-dir"},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+		}).To(Panic())
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("fatally logs if the drain URL is not provided", func() {
+		Expect(func() {
+			command.PushOrgDrain(
+				cli,
+				[]string{"--path", "some-temp-dir"},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Invalid arguments, expected 1, got 0."))
+	})
+})
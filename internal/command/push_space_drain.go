@@ -1,8 +1,11 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -21,10 +24,17 @@ type RefreshTokenFetcher interface {
 }
 
 type pushSpaceDrainOpts struct {
-	DrainName string `long:"drain-name"`
-	DrainURL  string
-	Path      string `long:"path"`
-	DrainType string `long:"type"`
+	DrainName       string `long:"drain-name"`
+	DrainURL        string
+	Path            string `long:"path"`
+	DrainType       string `long:"type"`
+	SkipValidation  bool   `long:"skip-validation"`
+	Include         string `long:"include"`
+	Exclude         string `long:"exclude"`
+	ExcludeApps     string `long:"exclude-apps"`
+	ClientID        string `long:"client-id"`
+	ClientSecretEnv string `long:"client-secret-env"`
+	CredentialStore string `long:"credential-store"`
 }
 
 func PushSpaceDrain(
@@ -35,8 +45,9 @@ func PushSpaceDrain(
 	log Logger,
 ) {
 	opts := pushSpaceDrainOpts{
-		DrainType: "all",
-		DrainName: "space-drain",
+		DrainType:       "all",
+		DrainName:       "space-drain",
+		CredentialStore: "user-provided",
 	}
 
 	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
@@ -50,16 +61,61 @@ func PushSpaceDrain(
 	}
 
 	opts.DrainURL = args[0]
+	extraEnvs := drainAppEnvs(opts, log)
 
 	app, _ := cli.GetApp(opts.DrainName)
 	if app.Name == opts.DrainName {
 		log.Fatalf("A drain with that name already exists. Use --drain-name to create a drain with a different name.")
 	}
 
-	pushDrain(cli, opts.DrainName, "space_drain", nil, opts, d, f, log)
+	pushDrain(cli, opts.DrainName, "space_drain", "space", extraEnvs, opts, d, f, log)
 }
 
-func pushDrain(cli plugin.CliConnection, appName, command string, extraEnvs [][]string, opts pushSpaceDrainOpts, d Downloader, f RefreshTokenFetcher, log Logger) {
+// drainAppEnvs validates the options shared by the space and org drains and
+// returns the env vars they map to.
+func drainAppEnvs(opts pushSpaceDrainOpts, log Logger) [][]string {
+	var extraEnvs [][]string
+	if opts.SkipValidation {
+		extraEnvs = append(extraEnvs, []string{"SKIP_DRAIN_URL_VALIDATION", "true"})
+	} else {
+		validateDrainURL(opts.DrainURL, log)
+	}
+
+	if opts.Include != "" {
+		extraEnvs = append(extraEnvs, []string{"INCLUDE_LABEL_SELECTOR", opts.Include})
+	}
+
+	if opts.Exclude != "" {
+		extraEnvs = append(extraEnvs, []string{"EXCLUDE_LABEL_SELECTOR", opts.Exclude})
+	}
+
+	if opts.ExcludeApps != "" {
+		if _, err := regexp.Compile(opts.ExcludeApps); err != nil {
+			log.Fatalf("Invalid --exclude-apps regex: %s", err)
+		}
+		extraEnvs = append(extraEnvs, []string{"EXCLUDE_APP_NAME_REGEX", opts.ExcludeApps})
+	}
+
+	if (opts.ClientID == "") != (opts.ClientSecretEnv == "") {
+		log.Fatalf("--client-id and --client-secret-env must be given together.")
+	}
+
+	switch opts.CredentialStore {
+	case "user-provided", "credhub", "env":
+	default:
+		log.Fatalf("Invalid --credential-store %s, expected user-provided, credhub or env.", opts.CredentialStore)
+	}
+
+	// The secret is read from the environment so it is not visible in the
+	// shell history or the process list.
+	if opts.ClientSecretEnv != "" && os.Getenv(opts.ClientSecretEnv) == "" {
+		log.Fatalf("Environment variable %s is not set.", opts.ClientSecretEnv)
+	}
+
+	return extraEnvs
+}
+
+func pushDrain(cli plugin.CliConnection, appName, command, scope string, extraEnvs [][]string, opts pushSpaceDrainOpts, d Downloader, f RefreshTokenFetcher, log Logger) {
 	if opts.Path == "" {
 		log.Printf("Downloading latest space drain from github...")
 		opts.Path = path.Dir(d.Download(command))
@@ -85,9 +141,20 @@ func pushDrain(cli plugin.CliConnection, appName, command string, extraEnvs [][]
 		log.Fatalf("%s", err)
 	}
 
-	refreshToken, err := f.RefreshToken()
-	if err != nil {
-		log.Fatalf("%s", err)
+	// With client credentials the drain app fetches its own tokens and the
+	// client secret is stored instead of the user's refresh token.
+	clientID := opts.ClientID
+	var authEnvs [][]string
+	if clientID == "" {
+		clientID = "cf"
+		refreshToken, err := f.RefreshToken()
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		authEnvs = storeCredential(cli, appName, opts.CredentialStore, "refresh_token", refreshToken, log)
+	} else {
+		secret := os.Getenv(opts.ClientSecretEnv)
+		authEnvs = storeCredential(cli, appName, opts.CredentialStore, "client_secret", secret, log)
 	}
 
 	sharedEnvs := [][]string{
@@ -97,13 +164,13 @@ func pushDrain(cli plugin.CliConnection, appName, command string, extraEnvs [][]
 		{"DRAIN_TYPE", opts.DrainType},
 		{"API_ADDR", api},
 		{"UAA_ADDR", strings.Replace(api, "api", "uaa", 1)},
-		{"CLIENT_ID", "cf"},
-		{"REFRESH_TOKEN", refreshToken},
+		{"CLIENT_ID", clientID},
 		{"SKIP_CERT_VERIFY", strconv.FormatBool(skipCertVerify)},
-		{"DRAIN_SCOPE", "space"},
+		{"DRAIN_SCOPE", scope},
 	}
 
-	envs := append(sharedEnvs, extraEnvs...)
+	envs := append(sharedEnvs, authEnvs...)
+	envs = append(envs, extraEnvs...)
 	for _, env := range envs {
 		_, err := cli.CliCommandWithoutTerminalOutput("set-env", appName, env[0], env[1])
 		if err != nil {
@@ -114,6 +181,59 @@ func pushDrain(cli plugin.CliConnection, appName, command string, extraEnvs [][]
 	cli.CliCommand("start", appName)
 }
 
+// storeCredential stores the refresh token or client secret, named by key,
+// for the drain app and returns the env vars that tell the app where to
+// find it. The service stores keep the credential out of the app's
+// environment.
+func storeCredential(cli plugin.CliConnection, appName, store, key, value string, log Logger) [][]string {
+	if store == "env" {
+		return [][]string{
+			{"CREDENTIAL_STORE", store},
+			{strings.ToUpper(key), value},
+		}
+	}
+
+	credentials, err := json.Marshal(map[string]string{key: value})
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	serviceName := credentialServiceName(appName)
+	_, err = cli.GetService(serviceName)
+	exists := err == nil
+
+	var command []string
+	switch {
+	case store == "credhub" && exists:
+		command = []string{"update-service", serviceName, "-c", string(credentials)}
+	case store == "credhub":
+		command = []string{"create-service", "credhub", "default", serviceName, "-c", string(credentials)}
+	case exists:
+		command = []string{"update-user-provided-service", serviceName, "-p", string(credentials)}
+	default:
+		command = []string{"create-user-provided-service", serviceName, "-p", string(credentials)}
+	}
+
+	if _, err := cli.CliCommandWithoutTerminalOutput(command...); err != nil {
+		log.Fatalf("Failed to store the %s in service %s: %s", strings.Replace(key, "_", " ", -1), serviceName, err)
+	}
+
+	if _, err := cli.CliCommandWithoutTerminalOutput("bind-service", appName, serviceName); err != nil {
+		log.Fatalf("Failed to bind service %s: %s", serviceName, err)
+	}
+
+	return [][]string{
+		{"CREDENTIAL_STORE", store},
+		{"CREDENTIAL_SERVICE_NAME", serviceName},
+	}
+}
+
+// credentialServiceName is the name of the service that holds the refresh
+// token or client secret of the given drain app.
+func credentialServiceName(appName string) string {
+	return appName + "-credentials"
+}
+
 func currentSpace(cli plugin.CliConnection, log Logger) plugin_models.Space {
 	space, err := cli.GetCurrentSpace()
 	if err != nil {
@@ -2,6 +2,7 @@ package command_test
 
 import (
 	"errors"
+	"os"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -23,6 +24,7 @@ var _ = Describe("PushSpaceDrain", func() {
 		cli = newStubCliConnection()
 		cli.currentSpaceGuid = "space-guid"
 		cli.getAppError = errors.New("app not found")
+		cli.getServiceError = errors.New("service not found")
 		cli.apiEndpoint = "https://api.something.com"
 		downloader = newStubDownloader()
 		downloader.path = "/downloaded/temp/dir/space_drain"
@@ -64,7 +66,10 @@ var _ = Describe("PushSpaceDrain", func() {
 			[]string{"set-env", "some-drain", "API_ADDR", "https://api.something.com"},
 			[]string{"set-env", "some-drain", "UAA_ADDR", "https://uaa.something.com"},
 			[]string{"set-env", "some-drain", "CLIENT_ID", "cf"},
-			[]string{"set-env", "some-drain", "REFRESH_TOKEN", "some-refresh-token"},
+			[]string{"create-user-provided-service", "some-drain-credentials", "-p", `{"refresh_token":"some-refresh-token"}`},
+			[]string{"bind-service", "some-drain", "some-drain-credentials"},
+			[]string{"set-env", "some-drain", "CREDENTIAL_STORE", "user-provided"},
+			[]string{"set-env", "some-drain", "CREDENTIAL_SERVICE_NAME", "some-drain-credentials"},
 			[]string{"set-env", "some-drain", "SKIP_CERT_VERIFY", "false"},
 			[]string{"set-env", "some-drain", "DRAIN_SCOPE", "space"},
 		))
@@ -136,7 +141,10 @@ var _ = Describe("PushSpaceDrain", func() {
 			[]string{"set-env", "some-drain", "API_ADDR", "https://api.something.com"},
 			[]string{"set-env", "some-drain", "UAA_ADDR", "https://uaa.something.com"},
 			[]string{"set-env", "some-drain", "CLIENT_ID", "cf"},
-			[]string{"set-env", "some-drain", "REFRESH_TOKEN", "some-refresh-token"},
+			[]string{"create-user-provided-service", "some-drain-credentials", "-p", `{"refresh_token":"some-refresh-token"}`},
+			[]string{"bind-service", "some-drain", "some-drain-credentials"},
+			[]string{"set-env", "some-drain", "CREDENTIAL_STORE", "user-provided"},
+			[]string{"set-env", "some-drain", "CREDENTIAL_SERVICE_NAME", "some-drain-credentials"},
 			[]string{"set-env", "some-drain", "SKIP_CERT_VERIFY", "false"},
 			[]string{"set-env", "some-drain", "DRAIN_SCOPE", "space"},
 		))
@@ -182,6 +190,333 @@ var _ = Describe("PushSpaceDrain", func() {
 		))
 	})
 
+	It("fatally logs if the drain URL fails validation", func() {
+		Expect(func() {
+			command.PushSpaceDrain(
+				cli,
+				[]string{
+					"syslog://some-drain",
+					"--path", "some-temp-dir",
+				},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Invalid syslog drain URL: missing port, syslog drains require a port"))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("skips validation and tells the space drain to skip it with --skip-validation", func() {
+		command.PushSpaceDrain(
+			cli,
+			[]string{
+				"syslog://some-drain",
+				"--path", "some-temp-dir",
+				"--skip-validation",
+			},
+			downloader,
+			refreshTokenFetcher,
+			logger,
+		)
+
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+			[]string{"set-env", "space-drain", "SKIP_DRAIN_URL_VALIDATION", "true"},
+		))
+	})
+
+	It("sets the include and exclude filters of the space drain", func() {
+		command.PushSpaceDrain(
+			cli,
+			[]string{
+				"https://some-drain",
+				"--path", "some-temp-dir",
+				"--include", "team=orders",
+				"--exclude", "load-generator=true",
+				"--exclude-apps", "^loadgen-",
+			},
+			downloader,
+			refreshTokenFetcher,
+			logger,
+		)
+
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+			[]string{"set-env", "space-drain", "INCLUDE_LABEL_SELECTOR", "team=orders"},
+		))
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+			[]string{"set-env", "space-drain", "EXCLUDE_LABEL_SELECTOR", "load-generator=true"},
+		))
+		Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+			[]string{"set-env", "space-drain", "EXCLUDE_APP_NAME_REGEX", "^loadgen-"},
+		))
+	})
+
+	It("fatally logs for an invalid --exclude-apps regex", func() {
+		Expect(func() {
+			command.PushSpaceDrain(
+				cli,
+				[]string{
+					"https://some-drain",
+					"--path", "some-temp-dir",
+					"--exclude-apps", "[",
+				},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(HavePrefix("Invalid --exclude-apps regex:"))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	Context("with client credentials", func() {
+		BeforeEach(func() {
+			os.Setenv("DRAIN_CLIENT_SECRET", "some-secret")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("DRAIN_CLIENT_SECRET")
+		})
+
+		It("configures the space drain with the client instead of a refresh token", func() {
+			command.PushSpaceDrain(
+				cli,
+				[]string{
+					"https://some-drain",
+					"--path", "some-temp-dir",
+					"--client-id", "drain-client",
+					"--client-secret-env", "DRAIN_CLIENT_SECRET",
+				},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+
+			Expect(refreshTokenFetcher.called).To(BeFalse())
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"set-env", "space-drain", "CLIENT_ID", "drain-client"},
+			))
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"create-user-provided-service", "space-drain-credentials", "-p", `{"client_secret":"some-secret"}`},
+			))
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"set-env", "space-drain", "CREDENTIAL_SERVICE_NAME", "space-drain-credentials"},
+			))
+			for _, args := range cli.cliCommandWithoutTerminalOutputArgs {
+				Expect(args).ToNot(ContainElement("REFRESH_TOKEN"))
+				Expect(args).ToNot(ContainElement("CLIENT_SECRET"))
+			}
+		})
+
+		It("sets the client secret as env var with the env credential store", func() {
+			command.PushSpaceDrain(
+				cli,
+				[]string{
+					"https://some-drain",
+					"--path", "some-temp-dir",
+					"--client-id", "drain-client",
+					"--client-secret-env", "DRAIN_CLIENT_SECRET",
+					"--credential-store", "env",
+				},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"set-env", "space-drain", "CLIENT_SECRET", "some-secret"},
+			))
+		})
+
+		It("fatally logs if the client secret can not be stored", func() {
+			cli.createServiceError = errors.New("some-error")
+
+			Expect(func() {
+				command.PushSpaceDrain(
+					cli,
+					[]string{
+						"https://some-drain",
+						"--path", "some-temp-dir",
+						"--client-id", "drain-client",
+						"--client-secret-env", "DRAIN_CLIENT_SECRET",
+					},
+					downloader,
+					refreshTokenFetcher,
+					logger,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Failed to store the client secret in service space-drain-credentials: some-error"))
+		})
+
+		It("fatally logs if the client secret env variable is not set", func() {
+			Expect(func() {
+				command.PushSpaceDrain(
+					cli,
+					[]string{
+						"https://some-drain",
+						"--path", "some-temp-dir",
+						"--client-id", "drain-client",
+						"--client-secret-env", "UNSET_CLIENT_SECRET",
+					},
+					downloader,
+					refreshTokenFetcher,
+					logger,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Environment variable UNSET_CLIENT_SECRET is not set."))
+			Expect(cli.cliCommandArgs).To(BeEmpty())
+		})
+
+		It("fatally logs if only the client id is given", func() {
+			Expect(func() {
+				command.PushSpaceDrain(
+					cli,
+					[]string{
+						"https://some-drain",
+						"--path", "some-temp-dir",
+						"--client-id", "drain-client",
+					},
+					downloader,
+					refreshTokenFetcher,
+					logger,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("--client-id and --client-secret-env must be given together."))
+		})
+	})
+
+	Context("with a credential store", func() {
+		It("updates the credential service if it already exists", func() {
+			cli.getServiceError = nil
+
+			command.PushSpaceDrain(
+				cli,
+				[]string{
+					"https://some-drain",
+					"--path", "some-temp-dir",
+				},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+
+			Expect(cli.getServicesName).To(Equal("space-drain-credentials"))
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"update-user-provided-service", "space-drain-credentials", "-p", `{"refresh_token":"some-refresh-token"}`},
+			))
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"bind-service", "space-drain", "space-drain-credentials"},
+			))
+		})
+
+		It("stores the refresh token in a credhub service", func() {
+			command.PushSpaceDrain(
+				cli,
+				[]string{
+					"https://some-drain",
+					"--path", "some-temp-dir",
+					"--credential-store", "credhub",
+				},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"create-service", "credhub", "default", "space-drain-credentials", "-c", `{"refresh_token":"some-refresh-token"}`},
+			))
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"set-env", "space-drain", "CREDENTIAL_STORE", "credhub"},
+			))
+		})
+
+		It("stores the refresh token in an env var of the app", func() {
+			command.PushSpaceDrain(
+				cli,
+				[]string{
+					"https://some-drain",
+					"--path", "some-temp-dir",
+					"--credential-store", "env",
+				},
+				downloader,
+				refreshTokenFetcher,
+				logger,
+			)
+
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"set-env", "space-drain", "REFRESH_TOKEN", "some-refresh-token"},
+			))
+			Expect(cli.cliCommandWithoutTerminalOutputArgs).To(ContainElement(
+				[]string{"set-env", "space-drain", "CREDENTIAL_STORE", "env"},
+			))
+			for _, args := range cli.cliCommandWithoutTerminalOutputArgs {
+				Expect(args).ToNot(ContainElement("bind-service"))
+			}
+		})
+
+		It("fatally logs for an unknown credential store", func() {
+			Expect(func() {
+				command.PushSpaceDrain(
+					cli,
+					[]string{
+						"https://some-drain",
+						"--path", "some-temp-dir",
+						"--credential-store", "vault",
+					},
+					downloader,
+					refreshTokenFetcher,
+					logger,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Invalid --credential-store vault, expected user-provided, credhub or env."))
+			Expect(cli.cliCommandArgs).To(BeEmpty())
+		})
+
+		It("fatally logs if the credential service can not be created", func() {
+			cli.createServiceError = errors.New("some-error")
+
+			Expect(func() {
+				command.PushSpaceDrain(
+					cli,
+					[]string{
+						"https://some-drain",
+						"--path", "some-temp-dir",
+					},
+					downloader,
+					refreshTokenFetcher,
+					logger,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Failed to store the refresh token in service space-drain-credentials: some-error"))
+		})
+
+		It("fatally logs if the credential service can not be bound", func() {
+			cli.bindServiceError = errors.New("some-error")
+
+			Expect(func() {
+				command.PushSpaceDrain(
+					cli,
+					[]string{
+						"https://some-drain",
+						"--path", "some-temp-dir",
+					},
+					downloader,
+					refreshTokenFetcher,
+					logger,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Failed to bind service space-drain-credentials: some-error"))
+		})
+	})
+
 	It("fatally logs if space-drain with same name already exists", func() {
 		cli.getAppError = nil
 		Expect(func() {
@@ -228,7 +563,8 @@ var _ = Describe("PushSpaceDrain", func() {
 		Entry("API_ADDR", "API_ADDR"),
 		Entry("UAA_ADDR", "UAA_ADDR"),
 		Entry("CLIENT_ID", "CLIENT_ID"),
-		Entry("REFRESH_TOKEN", "REFRESH_TOKEN"),
+		Entry("CREDENTIAL_STORE", "CREDENTIAL_STORE"),
+		Entry("CREDENTIAL_SERVICE_NAME", "CREDENTIAL_SERVICE_NAME"),
 		Entry("SKIP_CERT_VERIFY", "SKIP_CERT_VERIFY"),
 		Entry("DRAIN_SCOPE", "DRAIN_SCOPE"),
 	)
@@ -341,8 +677,9 @@ var _ = Describe("PushSpaceDrain", func() {
 })
 
 type stubRefreshTokenFetcher struct {
-	token string
-	err   error
+	token  string
+	err    error
+	called bool
 }
 
 func newStubRefreshTokenFetcher() *stubRefreshTokenFetcher {
@@ -350,5 +687,6 @@ func newStubRefreshTokenFetcher() *stubRefreshTokenFetcher {
 }
 
 func (s *stubRefreshTokenFetcher) RefreshToken() (string, error) {
+	s.called = true
 	return s.token, s.err
 }
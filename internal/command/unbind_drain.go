@@ -0,0 +1,45 @@
+package command
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// UnbindDrain removes the binding between a single app and a drain. Unlike
+// DeleteDrain, the drain and its other bindings are kept.
+func UnbindDrain(cli plugin.CliConnection, df DrainFetcher, args []string, log Logger) {
+	if len(args) != 2 {
+		log.Fatalf("Invalid arguments, expected 2, got %d.", len(args))
+	}
+
+	appName := args[0]
+	drainName := args[1]
+
+	space := currentSpace(cli, log)
+	drains, err := df.Drains(space.Guid)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	d, ok := findDrain(drains, drainName)
+	if !ok {
+		log.Fatalf("%s is not a valid drain.", drainName)
+	}
+
+	if !containsString(d.Apps, appName) {
+		log.Fatalf("%s is not bound to %s.", appName, drainName)
+	}
+
+	_, err = cli.CliCommand("unbind-service", appName, drainName)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package command_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/command"
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UnbindDrain", func() {
+	var (
+		logger       *stubLogger
+		cli          *stubCliConnection
+		drainFetcher *stubDrainFetcher
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		cli = newStubCliConnection()
+		cli.currentSpaceGuid = "space-guid"
+		drainFetcher = newStubDrainFetcher()
+		drainFetcher.drains = []drain.Drain{
+			{Name: "drain-name", Apps: []string{"app-1", "app-2"}},
+		}
+	})
+
+	It("calls unbind-service with the given app name and service", func() {
+		command.UnbindDrain(cli, drainFetcher, []string{"app-1", "drain-name"}, logger)
+
+		Expect(drainFetcher.spaceGuids).To(ConsistOf("space-guid"))
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"unbind-service", "app-1", "drain-name"},
+		}))
+	})
+
+	It("fatally logs if the app is not bound to the drain", func() {
+		Expect(func() {
+			command.UnbindDrain(cli, drainFetcher, []string{"app-3", "drain-name"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("app-3 is not bound to drain-name."))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("fatally logs if the drain does not exist", func() {
+		Expect(func() {
+			command.UnbindDrain(cli, drainFetcher, []string{"app-1", "unknown-drain-name"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("unknown-drain-name is not a valid drain."))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("fatally logs if it fails to unbind the service", func() {
+		cli.unbindServiceError = errors.New("unable to unbind")
+
+		Expect(func() {
+			command.UnbindDrain(cli, drainFetcher, []string{"app-1", "drain-name"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("unable to unbind"))
+	})
+
+	It("fatally logs if it fails to get existing drains", func() {
+		drainFetcher.err = errors.New("Failed to fetch drains.")
+
+		Expect(func() {
+			command.UnbindDrain(cli, drainFetcher, []string{"app-1", "drain-name"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Failed to fetch drains."))
+	})
+
+	It("fatally logs if it fails to get space guid", func() {
+		cli.currentSpaceError = errors.New("Failed to get space.")
+
+		Expect(func() {
+			command.UnbindDrain(cli, drainFetcher, []string{"app-1", "drain-name"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Failed to get space."))
+	})
+
+	It("expects to receive 2 arguments", func() {
+		Expect(func() {
+			command.UnbindDrain(cli, drainFetcher, []string{"app-1"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Invalid arguments, expected 2, got 1."))
+	})
+})
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"net/url"
+
+	"code.cloudfoundry.org/cli/plugin"
+	flags "github.com/jessevdk/go-flags"
+)
+
+type updateDrainOpts struct {
+	DrainURL       string `long:"url"`
+	DrainType      string `long:"type"`
+	Restage        bool   `long:"restage"`
+	SkipValidation bool   `long:"skip-validation"`
+}
+
+// UpdateDrain updates the syslog drain URL of an existing drain in place.
+// The drain keeps its bindings, so apps do not need to be bound again.
+func UpdateDrain(cli plugin.CliConnection, df DrainFetcher, args []string, log Logger) {
+	opts := updateDrainOpts{}
+
+	parser := flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+	args, err := parser.ParseArgs(args)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if len(args) != 1 {
+		log.Fatalf("Invalid arguments, expected 1, got %d.", len(args))
+	}
+
+	if opts.DrainURL == "" && opts.DrainType == "" {
+		log.Fatalf("Nothing to update, expected --url or --type.")
+	}
+
+	drainName := args[0]
+
+	space := currentSpace(cli, log)
+	drains, err := df.Drains(space.Guid)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	d, ok := findDrain(drains, drainName)
+	if !ok {
+		log.Fatalf("%s is not a valid drain.", drainName)
+	}
+
+	current, err := url.Parse(d.DrainURL)
+	if err != nil {
+		log.Fatalf("Invalid syslog drain URL: %s", err)
+	}
+
+	u := current
+	if opts.DrainURL != "" {
+		u, err = url.Parse(opts.DrainURL)
+		if err != nil {
+			log.Fatalf("Invalid syslog drain URL: %s", err)
+		}
+
+		// Keep the type of the drain unless the new URL or --type
+		// changes it.
+		if u.Query().Get("drain-type") == "" && current.Query().Get("drain-type") != "" {
+			setDrainType(u, d.Type, log)
+		}
+	}
+
+	if opts.DrainType != "" {
+		setDrainType(u, opts.DrainType, log)
+	}
+
+	if !opts.SkipValidation {
+		validateDrainURL(u.String(), log)
+	}
+
+	_, err = cli.CliCommand("update-user-provided-service", drainName, "-l", u.String())
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if !opts.Restage {
+		return
+	}
+
+	for _, app := range d.Apps {
+		_, err := cli.CliCommand("restage", app)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+	}
+}
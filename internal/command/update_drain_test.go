@@ -0,0 +1,154 @@
+package command_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/command"
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateDrain", func() {
+	var (
+		logger       *stubLogger
+		cli          *stubCliConnection
+		drainFetcher *stubDrainFetcher
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		cli = newStubCliConnection()
+		cli.currentSpaceGuid = "space-guid"
+		drainFetcher = newStubDrainFetcher()
+		drainFetcher.drains = []drain.Drain{
+			{
+				Name:     "drain-name",
+				Apps:     []string{"app-1", "app-2"},
+				Type:     "metrics",
+				DrainURL: "syslog://old.example.com:514?drain-type=metrics",
+			},
+			{
+				Name:     "untyped-drain",
+				Type:     "logs",
+				DrainURL: "syslog://old.example.com:514",
+			},
+		}
+	})
+
+	It("updates the drain URL and keeps the drain type", func() {
+		command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--url", "syslog-tls://new.example.com:6514"}, logger)
+
+		Expect(drainFetcher.spaceGuids).To(ConsistOf("space-guid"))
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"update-user-provided-service", "drain-name", "-l", "syslog-tls://new.example.com:6514?drain-type=metrics"},
+		}))
+	})
+
+	It("does not add a drain type to drains without one", func() {
+		command.UpdateDrain(cli, drainFetcher, []string{"untyped-drain", "--url", "syslog-tls://new.example.com:6514"}, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"update-user-provided-service", "untyped-drain", "-l", "syslog-tls://new.example.com:6514"},
+		}))
+	})
+
+	It("updates the drain type of the existing URL", func() {
+		command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--type", "all"}, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"update-user-provided-service", "drain-name", "-l", "syslog://old.example.com:514?drain-type=all"},
+		}))
+	})
+
+	It("updates the URL and the drain type", func() {
+		command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--url", "https://new.example.com", "--type", "logs"}, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"update-user-provided-service", "drain-name", "-l", "https://new.example.com?drain-type=logs"},
+		}))
+	})
+
+	It("restages the bound apps with --restage", func() {
+		command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--type", "all", "--restage"}, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"update-user-provided-service", "drain-name", "-l", "syslog://old.example.com:514?drain-type=all"},
+			{"restage", "app-1"},
+			{"restage", "app-2"},
+		}))
+	})
+
+	It("fatally logs for unknown drain types", func() {
+		Expect(func() {
+			command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--type", "garbage"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Invalid type: garbage"))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("fatally logs if the new URL fails validation", func() {
+		Expect(func() {
+			command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--url", "syslog://new.example.com"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Invalid syslog drain URL: missing port, syslog drains require a port"))
+		Expect(cli.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("skips validation with --skip-validation", func() {
+		command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--url", "syslog://new.example.com", "--skip-validation"}, logger)
+
+		Expect(cli.cliCommandArgs).To(Equal([][]string{
+			{"update-user-provided-service", "drain-name", "-l", "syslog://new.example.com?drain-type=metrics"},
+		}))
+	})
+
+	It("fatally logs if there is nothing to update", func() {
+		Expect(func() {
+			command.UpdateDrain(cli, drainFetcher, []string{"drain-name"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Nothing to update, expected --url or --type."))
+	})
+
+	It("fatally logs if the drain does not exist", func() {
+		Expect(func() {
+			command.UpdateDrain(cli, drainFetcher, []string{"unknown-drain", "--type", "all"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("unknown-drain is not a valid drain."))
+	})
+
+	It("fatally logs if it fails to fetch drains", func() {
+		drainFetcher.err = errors.New("omg error")
+
+		Expect(func() {
+			command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--type", "all"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("omg error"))
+	})
+
+	It("fatally logs if updating the service fails", func() {
+		cli.updateServiceError = errors.New("update failed")
+
+		Expect(func() {
+			command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--type", "all"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("update failed"))
+	})
+
+	It("fatally logs if restaging an app fails", func() {
+		cli.restageAppError = errors.New("restage failed")
+
+		Expect(func() {
+			command.UpdateDrain(cli, drainFetcher, []string{"drain-name", "--type", "all", "--restage"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("restage failed"))
+	})
+
+	It("expects to receive 1 argument", func() {
+		Expect(func() {
+			command.UpdateDrain(cli, drainFetcher, []string{"--type", "all"}, logger)
+		}).To(Panic())
+		Expect(logger.fatalfMessage).To(Equal("Invalid arguments, expected 1, got 0."))
+	})
+})
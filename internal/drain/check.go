@@ -0,0 +1,208 @@
+package drain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/rfc5424"
+)
+
+// Checker verifies that a drain URL accepts data by connecting to it over
+// the URL's scheme and sending a RFC 5424 test message.
+type Checker struct {
+	rootCAs *x509.CertPool
+	timeout time.Duration
+}
+
+func NewChecker(opts ...CheckerOption) *Checker {
+	c := &Checker{
+		timeout: 10 * time.Second,
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+type CheckerOption func(c *Checker)
+
+// WithCheckerRootCAs sets the certificate authorities used to validate the
+// certificate chain of syslog-tls and https drains. It defaults to the
+// system pool.
+func WithCheckerRootCAs(pool *x509.CertPool) CheckerOption {
+	return func(c *Checker) {
+		c.rootCAs = pool
+	}
+}
+
+// WithCheckerTimeout sets the timeout of each step of a check.
+func WithCheckerTimeout(d time.Duration) CheckerOption {
+	return func(c *Checker) {
+		c.timeout = d
+	}
+}
+
+// CheckStep is the outcome of a single step of a drain check.
+type CheckStep struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// CheckResult holds the steps of a drain check in the order they were run.
+// Steps following a failed step are not run.
+type CheckResult struct {
+	Steps []CheckStep
+}
+
+// Err returns the error of the failed step, if any.
+func (r CheckResult) Err() error {
+	for _, s := range r.Steps {
+		if s.Err != nil {
+			return fmt.Errorf("%s: %s", s.Name, s.Err)
+		}
+	}
+
+	return nil
+}
+
+var defaultPorts = map[string]string{
+	"syslog":     "514",
+	"syslog-tls": "6514",
+	"https":      "443",
+}
+
+func (c *Checker) Check(drainURL string) CheckResult {
+	var r CheckResult
+
+	u, err := url.Parse(drainURL)
+	if err != nil {
+		return r.add("parse", 0, err)
+	}
+
+	port, ok := defaultPorts[u.Scheme]
+	if !ok {
+		return r.add("parse", 0, fmt.Errorf("unsupported scheme %q", u.Scheme))
+	}
+
+	if u.Port() != "" {
+		port = u.Port()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("no addresses found for %s", u.Hostname())
+	}
+	r = r.add("dns", time.Since(start), err)
+	if err != nil {
+		return r
+	}
+
+	start = time.Now()
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(addrs[0].String(), port))
+	r = r.add("connect", time.Since(start), err)
+	if err != nil {
+		return r
+	}
+	defer conn.Close()
+
+	if u.Scheme != "syslog" {
+		start = time.Now()
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName: u.Hostname(),
+			RootCAs:    c.rootCAs,
+		})
+		tlsConn.SetDeadline(time.Now().Add(c.timeout))
+		err = tlsConn.Handshake()
+		r = r.add("tls handshake", time.Since(start), err)
+		if err != nil {
+			return r
+		}
+		conn = tlsConn
+	}
+
+	start = time.Now()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	if u.Scheme == "https" {
+		err = c.post(conn, u)
+	} else {
+		_, err = testMessage().WriteTo(conn)
+	}
+
+	return r.add("write", time.Since(start), err)
+}
+
+// post sends the test message as the body of a HTTP POST request, the same
+// way Loggregator writes to https drains.
+func (c *Checker) post(conn net.Conn, u *url.URL) error {
+	body, err := testMessage().MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Close = true
+
+	err = req.Write(conn)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (r CheckResult) add(name string, d time.Duration, err error) CheckResult {
+	r.Steps = append(r.Steps, CheckStep{
+		Name:     name,
+		Duration: d,
+		Err:      err,
+	})
+
+	return r
+}
+
+func testMessage() rfc5424.Message {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "cf-drain-cli"
+	}
+
+	return rfc5424.Message{
+		Priority:  rfc5424.User | rfc5424.Info,
+		Timestamp: time.Now(),
+		Hostname:  hostname,
+		AppName:   "cf-drain-cli",
+		ProcessID: "drain-check",
+		Message:   []byte("Test message from cf drain-check\n"),
+	}
+}
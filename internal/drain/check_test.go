@@ -0,0 +1,168 @@
+package drain_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/drain"
+	"code.cloudfoundry.org/cf-drain-cli/internal/testhelper"
+	"code.cloudfoundry.org/rfc5424"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		rootCAs *x509.CertPool
+		c       *drain.Checker
+	)
+
+	BeforeEach(func() {
+		cert, err := ioutil.ReadFile(testhelper.Cert("localhost.crt"))
+		Expect(err).ToNot(HaveOccurred())
+
+		rootCAs = x509.NewCertPool()
+		Expect(rootCAs.AppendCertsFromPEM(cert)).To(BeTrue())
+
+		c = drain.NewChecker(drain.WithCheckerRootCAs(rootCAs))
+	})
+
+	It("writes a test message to syslog drains", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer lis.Close()
+		msgs := acceptSyslog(lis)
+
+		r := c.Check(fmt.Sprintf("syslog://%s", lis.Addr()))
+		Expect(r.Err()).ToNot(HaveOccurred())
+		Expect(stepNames(r)).To(Equal([]string{"dns", "connect", "write"}))
+
+		var msg rfc5424.Message
+		Eventually(msgs).Should(Receive(&msg))
+		Expect(msg.AppName).To(Equal("cf-drain-cli"))
+		Expect(string(msg.Message)).To(ContainSubstring("cf drain-check"))
+	})
+
+	It("writes a test message to syslog-tls drains", func() {
+		lis, err := tls.Listen("tcp", "127.0.0.1:0", serverTLSConfig())
+		Expect(err).ToNot(HaveOccurred())
+		defer lis.Close()
+		msgs := acceptSyslog(lis)
+
+		r := c.Check(fmt.Sprintf("syslog-tls://%s?drain-type=all", lis.Addr()))
+		Expect(r.Err()).ToNot(HaveOccurred())
+		Expect(stepNames(r)).To(Equal([]string{"dns", "connect", "tls handshake", "write"}))
+
+		Eventually(msgs).Should(Receive())
+	})
+
+	It("posts a test message to https drains", func() {
+		bodies := make(chan string, 1)
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies <- string(body)
+		}))
+		server.TLS = serverTLSConfig()
+		server.StartTLS()
+		defer server.Close()
+
+		r := c.Check(server.URL + "/drain")
+		Expect(r.Err()).ToNot(HaveOccurred())
+		Expect(stepNames(r)).To(Equal([]string{"dns", "connect", "tls handshake", "write"}))
+
+		Eventually(bodies).Should(Receive(ContainSubstring("cf drain-check")))
+	})
+
+	It("fails the write step for non-2XX https responses", func() {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		server.TLS = serverTLSConfig()
+		server.StartTLS()
+		defer server.Close()
+
+		r := c.Check(server.URL)
+		Expect(r.Err()).To(MatchError("write: unexpected status code 403"))
+	})
+
+	It("fails the tls handshake step when the certificate chain is not trusted", func() {
+		lis, err := tls.Listen("tcp", "127.0.0.1:0", serverTLSConfig())
+		Expect(err).ToNot(HaveOccurred())
+		defer lis.Close()
+		acceptSyslog(lis)
+
+		c = drain.NewChecker(drain.WithCheckerRootCAs(x509.NewCertPool()))
+		r := c.Check(fmt.Sprintf("syslog-tls://%s", lis.Addr()))
+
+		Expect(r.Err()).To(HaveOccurred())
+		Expect(stepNames(r)).To(Equal([]string{"dns", "connect", "tls handshake"}))
+	})
+
+	It("fails the connect step when nothing is listening", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		addr := lis.Addr().String()
+		lis.Close()
+
+		r := c.Check(fmt.Sprintf("syslog://%s", addr))
+
+		Expect(r.Err()).To(HaveOccurred())
+		Expect(stepNames(r)).To(Equal([]string{"dns", "connect"}))
+	})
+
+	It("fails for unsupported schemes", func() {
+		r := c.Check("sylog://127.0.0.1:514")
+
+		Expect(r.Err()).To(MatchError(`parse: unsupported scheme "sylog"`))
+	})
+})
+
+func stepNames(r drain.CheckResult) []string {
+	var names []string
+	for _, s := range r.Steps {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func serverTLSConfig() *tls.Config {
+	cert, err := tls.LoadX509KeyPair(
+		testhelper.Cert("localhost.crt"),
+		testhelper.Cert("localhost.key"),
+	)
+	Expect(err).ToNot(HaveOccurred())
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+}
+
+func acceptSyslog(lis net.Listener) <-chan rfc5424.Message {
+	msgs := make(chan rfc5424.Message, 10)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				var msg rfc5424.Message
+				_, err := msg.ReadFrom(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				msgs <- msg
+			}()
+		}
+	}()
+
+	return msgs
+}
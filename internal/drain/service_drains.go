@@ -2,7 +2,6 @@ package drain
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/url"
 	"strings"
 
@@ -10,14 +9,14 @@ import (
 )
 
 type ServiceDrainLister struct {
-	c                 cloudcontroller.Curler
-	appNameBatchLimit int
+	c          cloudcontroller.Curler
+	batchLimit int
 }
 
 func NewServiceDrainLister(c cloudcontroller.Curler, opts ...ServiceDrainListerOption) *ServiceDrainLister {
 	dl := &ServiceDrainLister{
-		c:                 c,
-		appNameBatchLimit: 100,
+		c:          c,
+		batchLimit: 100,
 	}
 
 	for _, o := range opts {
@@ -29,9 +28,11 @@ func NewServiceDrainLister(c cloudcontroller.Curler, opts ...ServiceDrainListerO
 
 type ServiceDrainListerOption func(l *ServiceDrainLister)
 
-func WithServiceDrainAppBatchLimit(limit int) ServiceDrainListerOption {
+// WithServiceDrainBatchLimit sets the maximum number of service instance
+// GUIDs sent in a single service credential bindings request.
+func WithServiceDrainBatchLimit(limit int) ServiceDrainListerOption {
 	return func(l *ServiceDrainLister) {
-		l.appNameBatchLimit = limit
+		l.batchLimit = limit
 	}
 }
 
@@ -42,49 +43,69 @@ type Drain struct {
 	AppGuids []string
 	Type     string
 	DrainURL string
+	Tags     []string
+}
+
+// spaceDrainTagPrefix prefixes the tag of the drains created by a space or
+// org drain app.
+const spaceDrainTagPrefix = "space-drain:"
+
+// SpaceDrainTag is the tag of the drains created by the space or org drain
+// app with the given GUID.
+func SpaceDrainTag(appGuid string) string {
+	return spaceDrainTagPrefix + appGuid
+}
+
+// CreatedBySpaceDrain reports whether the drain was created by a space or
+// org drain app.
+func (d Drain) CreatedBySpaceDrain() bool {
+	for _, t := range d.Tags {
+		if strings.HasPrefix(t, spaceDrainTagPrefix) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (l *ServiceDrainLister) Drains(spaceGuid string) ([]Drain, error) {
-	var url string
-	url = fmt.Sprintf("/v2/user_provided_service_instances?q=space_guid:%s", spaceGuid)
-	instances, err := l.fetchServiceInstances(url)
+	params := url.Values{
+		"type":        {"user-provided"},
+		"space_guids": {spaceGuid},
+	}
+	instances, err := l.fetchServiceInstances("/v3/service_instances?" + params.Encode())
 	if err != nil {
 		return nil, err
 	}
 
-	var appGuids []string
+	var guids []string
 	var drains []Drain
 	for _, s := range instances {
-		if s.Entity.SyslogDrainURL == "" {
+		if s.SyslogDrainURL == "" {
 			continue
 		}
 
-		apps, err := l.fetchApps(s.Entity.ServiceBindingsURL)
-		if err != nil {
-			return nil, err
-		}
-		appGuids = append(appGuids, apps...)
-
-		drainType, err := l.TypeFromDrainURL(s.Entity.SyslogDrainURL)
+		drainType, err := l.TypeFromDrainURL(s.SyslogDrainURL)
 		if err != nil {
 			return nil, err
 		}
 
 		drain, err := l.buildDrain(
-			apps,
-			s.Entity.Name,
-			s.MetaData.Guid,
+			s.Name,
+			s.Guid,
 			drainType,
-			s.Entity.SyslogDrainURL,
+			s.SyslogDrainURL,
 		)
 		if err != nil {
 			return nil, err
 		}
+		drain.Tags = s.Tags
 
+		guids = append(guids, s.Guid)
 		drains = append(drains, drain)
 	}
 
-	appNames, err := l.fetchBatchAppNames(appGuids)
+	bindings, err := l.fetchBatchBindings(guids)
 	if err != nil {
 		return nil, err
 	}
@@ -92,28 +113,28 @@ func (l *ServiceDrainLister) Drains(spaceGuid string) ([]Drain, error) {
 	var namedDrains []Drain
 	for _, d := range drains {
 		var names []string
-		var guids []string
-		for _, guid := range d.Apps {
-			names = append(names, appNames[guid])
-			guids = append(guids, guid)
+		var appGuids []string
+		for _, app := range bindings[d.Guid] {
+			names = append(names, app.Name)
+			appGuids = append(appGuids, app.Guid)
 		}
 		d.Apps = uniqueStringSlice(names)
-		d.AppGuids = uniqueStringSlice(guids)
+		d.AppGuids = uniqueStringSlice(appGuids)
 		namedDrains = append(namedDrains, d)
 	}
 
 	return namedDrains, nil
 }
 
-func (l *ServiceDrainLister) fetchServiceInstances(url string) ([]userProvidedServiceInstance, error) {
-	instances := []userProvidedServiceInstance{}
+func (l *ServiceDrainLister) fetchServiceInstances(url string) ([]serviceInstance, error) {
+	instances := []serviceInstance{}
 	for url != "" {
 		resp, err := l.c.Curl(url, "GET", "")
 		if err != nil {
 			return nil, err
 		}
 
-		var services userProvidedServiceInstancesResponse
+		var services serviceInstancesResponse
 		err = json.Unmarshal(resp, &services)
 		if err != nil {
 			return nil, err
@@ -121,89 +142,74 @@ func (l *ServiceDrainLister) fetchServiceInstances(url string) ([]userProvidedSe
 
 		instances = append(instances, services.Resources...)
 
-		url = services.NextURL
+		url = services.Pagination.Next.Path()
 	}
 	return instances, nil
 }
 
-func (l *ServiceDrainLister) fetchApps(url string) ([]string, error) {
-	var apps []string
-	for url != "" {
-		resp, err := l.c.Curl(url, "GET", "")
-		if err != nil {
-			return nil, err
-		}
-
-		var serviceBindingsResponse serviceBindingsResponse
-		err = json.Unmarshal(resp, &serviceBindingsResponse)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, r := range serviceBindingsResponse.Resources {
-			apps = append(apps, r.Entity.AppGuid)
-		}
-
-		url = serviceBindingsResponse.NextURL
-	}
-
-	return apps, nil
-}
-
-func (l *ServiceDrainLister) fetchBatchAppNames(guids []string) (map[string]string, error) {
-	guids = uniqueStringSlice(guids)
-
-	allAppNames := make(map[string]string)
-	for i := 0; i < len(guids); i += l.appNameBatchLimit {
-		end := i + l.appNameBatchLimit
+func (l *ServiceDrainLister) fetchBatchBindings(guids []string) (map[string][]appData, error) {
+	allBindings := make(map[string][]appData)
+	for i := 0; i < len(guids); i += l.batchLimit {
+		end := i + l.batchLimit
 
 		if end > len(guids) {
 			end = len(guids)
 		}
 
-		appNames, err := l.fetchAppNames(guids[i:end])
+		bindings, err := l.fetchBindings(guids[i:end])
 		if err != nil {
 			return nil, err
 		}
 
-		for k, v := range appNames {
-			allAppNames[k] = v
+		for k, v := range bindings {
+			allBindings[k] = append(allBindings[k], v...)
 		}
 	}
 
-	return allAppNames, nil
+	return allBindings, nil
 }
 
-func (l *ServiceDrainLister) fetchAppNames(guids []string) (map[string]string, error) {
-	if len(guids) == 0 {
-		return nil, nil
-	}
-
+// fetchBindings returns the apps bound to each of the given service
+// instances, keyed by service instance GUID.
+func (l *ServiceDrainLister) fetchBindings(guids []string) (map[string][]appData, error) {
 	params := url.Values{
-		"guids": {strings.Join(guids, ",")},
+		"type":                   {"app"},
+		"service_instance_guids": {strings.Join(guids, ",")},
+		"include":                {"app"},
 	}
 
-	url := "/v3/apps?" + params.Encode()
-	apps := make(map[string]string)
+	url := "/v3/service_credential_bindings?" + params.Encode()
+	bindings := make(map[string][]appData)
 	for url != "" {
 		resp, err := l.c.Curl(url, "GET", "")
 		if err != nil {
 			return nil, err
 		}
 
-		var appsResp appsResponse
-		err = json.Unmarshal(resp, &appsResp)
+		var bindingsResp serviceCredentialBindingsResponse
+		err = json.Unmarshal(resp, &bindingsResp)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, a := range appsResp.Apps {
-			apps[a.Guid] = a.Name
+		appNames := make(map[string]string)
+		for _, a := range bindingsResp.Included.Apps {
+			appNames[a.Guid] = a.Name
+		}
+
+		for _, b := range bindingsResp.Resources {
+			instanceGuid := b.Relationships.ServiceInstance.Data.Guid
+			appGuid := b.Relationships.App.Data.Guid
+			bindings[instanceGuid] = append(bindings[instanceGuid], appData{
+				Name: appNames[appGuid],
+				Guid: appGuid,
+			})
 		}
-		url = appsResp.Pagination.Next
+
+		url = bindingsResp.Pagination.Next.Path()
 	}
 
-	return apps, nil
+	return bindings, nil
 }
 
 func (l *ServiceDrainLister) TypeFromDrainURL(URL string) (string, error) {
@@ -220,51 +226,49 @@ func (l *ServiceDrainLister) TypeFromDrainURL(URL string) (string, error) {
 	}
 }
 
-func (l *ServiceDrainLister) buildDrain(apps []string, name, guid, drainType, drainURL string) (Drain, error) {
+func (l *ServiceDrainLister) buildDrain(name, guid, drainType, drainURL string) (Drain, error) {
 	return Drain{
 		Name:     name,
 		Guid:     guid,
-		Apps:     apps,
 		Type:     drainType,
 		DrainURL: drainURL,
 	}, nil
 }
 
-type userProvidedServiceInstancesResponse struct {
-	NextURL   string                        `json:"next_url"`
-	Resources []userProvidedServiceInstance `json:"resources"`
-}
-
-type userProvidedServiceInstance struct {
-	MetaData struct {
-		Guid string `json:"guid"`
-	} `json:"metadata"`
-	Entity struct {
-		Name               string `json:"name"`
-		ServiceBindingsURL string `json:"service_bindings_url"`
-		SyslogDrainURL     string `json:"syslog_drain_url"`
-	} `json:"entity"`
-}
-
-type serviceBindingsResponse struct {
-	NextURL   string           `json:"next_url"`
-	Resources []serviceBinding `json:"resources"`
+type serviceInstancesResponse struct {
+	Pagination cloudcontroller.Pagination `json:"pagination"`
+	Resources  []serviceInstance          `json:"resources"`
 }
 
-type serviceBinding struct {
-	Entity struct {
-		AppGuid string `json:"app_guid"`
-		AppUrl  string `json:"app_url"`
-	} `json:"entity"`
+type serviceInstance struct {
+	Guid           string   `json:"guid"`
+	Name           string   `json:"name"`
+	SyslogDrainURL string   `json:"syslog_drain_url"`
+	Tags           []string `json:"tags"`
 }
 
-type appsResponse struct {
-	Apps       []appData  `json:"resources"`
-	Pagination Pagination `json:"pagination"`
+type serviceCredentialBindingsResponse struct {
+	Pagination cloudcontroller.Pagination `json:"pagination"`
+	Resources  []serviceCredentialBinding `json:"resources"`
+	Included   struct {
+		Apps []appData `json:"apps"`
+	} `json:"included"`
 }
 
-type Pagination struct {
-	Next string `json:"next"`
+type serviceCredentialBinding struct {
+	Guid          string `json:"guid"`
+	Relationships struct {
+		App struct {
+			Data struct {
+				Guid string `json:"guid"`
+			} `json:"data"`
+		} `json:"app"`
+		ServiceInstance struct {
+			Data struct {
+				Guid string `json:"guid"`
+			} `json:"data"`
+		} `json:"service_instance"`
+	} `json:"relationships"`
 }
 
 type appData struct {
@@ -21,151 +21,134 @@ var _ = Describe("ListDrainsClient", func() {
 		curler = newStubCurler()
 		c = drain.NewServiceDrainLister(
 			curler,
-			drain.WithServiceDrainAppBatchLimit(3),
+			drain.WithServiceDrainBatchLimit(3),
 		)
 	})
 
 	It("only displays syslog services", func() {
 		var noDrainServiceInstancesJSON = `{
-		   "total_results": 1,
-		   "total_pages": 1,
-		   "prev_url": null,
-		   "next_url": null,
+		   "pagination": {
+		      "total_results": 1,
+		      "total_pages": 1,
+		      "next": null,
+		      "previous": null
+		   },
 		   "resources": [
 		      {
-		         "entity": {
-		            "name": "other-service-1",
-		            "syslog_drain_url": "",
-		            "service_bindings_url": "/v2/user_provided_service_instances/other-service-1/service_bindings"
-		         }
+		         "guid": "other-guid-1",
+		         "name": "other-service-1",
+		         "type": "user-provided",
+		         "syslog_drain_url": ""
 		      }
 		   ]
 		}`
-		key = "/v2/user_provided_service_instances?q=space_guid:space-guid"
+		key = "/v3/service_instances?space_guids=space-guid&type=user-provided"
 		curler.resps[key] = noDrainServiceInstancesJSON
 		d, err := c.Drains("space-guid")
 
 		Expect(err).ToNot(HaveOccurred())
 		Expect(d).To(HaveLen(0))
+		Expect(curler.URLs).To(ConsistOf(key))
 	})
 
-	Context("requesting more than apps than the batch limit", func() {
+	Context("requesting more service instances than the batch limit", func() {
 		BeforeEach(func() {
-			key = "/v2/user_provided_service_instances?q=space_guid:space-guid"
-			curler.resps[key] = serviceInstancesJSON2
+			key = "/v3/service_instances?space_guids=space-guid&type=user-provided"
+			curler.resps[key] = serviceInstancesJSONBatch
 
-			key = "/v2/user_provided_service_instances/drain-2/service_bindings"
-			curler.resps[key] = serviceBindingsJSON3
+			key = "/v3/service_credential_bindings?include=app&service_instance_guids=guid-1,guid-2,guid-3&type=app"
+			curler.resps[key] = bindingsJSONBatch1
 
-			key = "/v3/apps?guids=app-1,app-2,app-3"
-			curler.resps[key] = appJSONcall1
-
-			key = "/v3/apps?guids=app-4"
-			curler.resps[key] = appJSONcall2
+			key = "/v3/service_credential_bindings?include=app&service_instance_guids=guid-4&type=app"
+			curler.resps[key] = bindingsJSONBatch2
 		})
 
 		It("returns every drain", func() {
 			d, err := c.Drains("space-guid")
 			Expect(err).ToNot(HaveOccurred())
-			Expect(d).To(HaveLen(1))
-
-			Expect(d[0].Name).To(Equal("drain-2"))
-			Expect(d[0].Guid).To(Equal("guid-2"))
-			Expect(d[0].Apps).To(Equal([]string{"My App One", "My App Two", "My App Three", "My App Four"}))
-			Expect(d[0].AppGuids).To(Equal([]string{"app-1", "app-2", "app-3", "app-4"}))
-			Expect(d[0].Type).To(Equal("logs"))
-			Expect(d[0].DrainURL).To(Equal("syslog://your-app.cf-app.com"))
-
-			Expect(curler.URLs[2:]).To(Equal([]string{
-				"/v3/apps?guids=app-1,app-2,app-3",
-				"/v3/apps?guids=app-4",
+			Expect(d).To(HaveLen(4))
+
+			Expect(d[0].Name).To(Equal("drain-1"))
+			Expect(d[0].Apps).To(Equal([]string{"My App One", "My App Two"}))
+			Expect(d[0].AppGuids).To(Equal([]string{"app-1", "app-2"}))
+
+			Expect(d[1].Name).To(Equal("drain-2"))
+			Expect(d[1].Apps).To(BeEmpty())
+			Expect(d[1].AppGuids).To(BeEmpty())
+
+			Expect(d[2].Name).To(Equal("drain-3"))
+			Expect(d[2].Apps).To(Equal([]string{"My App Three"}))
+
+			Expect(d[3].Name).To(Equal("drain-4"))
+			Expect(d[3].Guid).To(Equal("guid-4"))
+			Expect(d[3].Apps).To(Equal([]string{"My App Four"}))
+			Expect(d[3].AppGuids).To(Equal([]string{"app-4"}))
+			Expect(d[3].Type).To(Equal("all"))
+			Expect(d[3].DrainURL).To(Equal("syslog-tls://your-app.cf-app.com:6514?drain-type=all"))
+			Expect(d[3].Tags).To(Equal([]string{"space-drain:some-app-guid"}))
+
+			Expect(curler.URLs[1:]).To(Equal([]string{
+				"/v3/service_credential_bindings?include=app&service_instance_guids=guid-1,guid-2,guid-3&type=app",
+				"/v3/service_credential_bindings?include=app&service_instance_guids=guid-4&type=app",
 			}))
 
-			// 8 => 2 service fetch + (2 app fetches) +  (4 app name fetches)
-			Expect(curler.methods).To(ConsistOf("GET", "GET", "GET", "GET"))
-			Expect(curler.bodies).To(ConsistOf("", "", "", ""))
+			// 3 => 1 service instance fetch + 2 binding fetches
+			Expect(curler.methods).To(ConsistOf("GET", "GET", "GET"))
+			Expect(curler.bodies).To(ConsistOf("", "", ""))
 		})
 	})
 
 	Context("when requesting service instances succeeds", func() {
 		BeforeEach(func() {
-			key = "/v2/user_provided_service_instances?q=space_guid:space-guid"
+			key = "/v3/service_instances?space_guids=space-guid&type=user-provided"
 			curler.resps[key] = serviceInstancesJSONpage1
-			key = "/v2/user_provided_service_instances?q=space_guid:space-guid&page:2"
+			key = "/v3/service_instances?page=2&per_page=1&space_guids=space-guid&type=user-provided"
 			curler.resps[key] = serviceInstancesJSONpage2
 		})
 
-		Context("requesting service bindings succeeds", func() {
+		Context("requesting service credential bindings succeeds", func() {
 			BeforeEach(func() {
-				key = "/v2/user_provided_service_instances/drain-1/service_bindings"
-				curler.resps[key] = serviceBindingsJSON1page1
-				key = "/v2/user_provided_service_instances/drain-1/service_bindings&page:2"
-				curler.resps[key] = serviceBindingsJSON1page2
-				key = "/v2/user_provided_service_instances/drain-2/service_bindings"
-				curler.resps[key] = serviceBindingsJSON2
-			})
-
-			Context("requesting app names succeeds", func() {
-				BeforeEach(func() {
-					key = "/v3/apps?guids=app-1,app-2"
-					curler.resps[key] = appJSONpage1
-
-					key = "/v3/apps?guids=app-1,app-2&page=2"
-					curler.resps[key] = appJSONpage2
-				})
-
-				It("returns every drain", func() {
-					d, err := c.Drains("space-guid")
-					Expect(err).ToNot(HaveOccurred())
-					Expect(d).To(HaveLen(2))
-
-					Expect(d[0].Name).To(Equal("drain-1"))
-					Expect(d[0].Guid).To(Equal("guid-1"))
-					Expect(d[0].Apps).To(Equal([]string{"My App One", "My App Two"}))
-					Expect(d[0].AppGuids).To(Equal([]string{"app-1", "app-2"}))
-					Expect(d[0].Type).To(Equal("logs"))
-					Expect(d[0].DrainURL).To(Equal("syslog://your-app.cf-app.com"))
-
-					Expect(d[1].Name).To(Equal("drain-2"))
-					Expect(d[1].Guid).To(Equal("guid-2"))
-					Expect(d[1].Apps).To(Equal([]string{"My App One"}))
-					Expect(d[1].AppGuids).To(Equal([]string{"app-1"}))
-					Expect(d[1].Type).To(Equal("metrics"))
-					Expect(d[1].DrainURL).To(Equal("https://your-app2.cf-app.com?drain-type=metrics"))
-
-					// 7 => 2 service fetch + (2 app fetches) +  (3 app name fetches)
-					Expect(curler.methods).To(ConsistOf("GET", "GET", "GET", "GET", "GET", "GET", "GET"))
-					Expect(curler.bodies).To(ConsistOf("", "", "", "", "", "", ""))
-				})
+				key = "/v3/service_credential_bindings?include=app&service_instance_guids=guid-1,guid-2&type=app"
+				curler.resps[key] = bindingsJSONpage1
+				key = "/v3/service_credential_bindings?include=app&page=2&per_page=2&service_instance_guids=guid-1,guid-2&type=app"
+				curler.resps[key] = bindingsJSONpage2
 			})
 
-			It("returns the error if requesting the apps fails", func() {
-				key = "/v3/apps?guids=app-1,app-2"
-				curler.errs[key] = errors.New("some error")
-
-				_, err := c.Drains("space-guid")
-				Expect(err).To(MatchError("some error"))
-			})
-
-			It("returns the error if unmarshalling the apps fails", func() {
-				key = "/v3/apps?guids=app-1,app-2"
-				curler.resps[key] = "not a json"
-
-				_, err := c.Drains("space-guid")
-				Expect(err).To(HaveOccurred())
+			It("returns every drain", func() {
+				d, err := c.Drains("space-guid")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(d).To(HaveLen(2))
+
+				Expect(d[0].Name).To(Equal("drain-1"))
+				Expect(d[0].Guid).To(Equal("guid-1"))
+				Expect(d[0].Apps).To(Equal([]string{"My App One", "My App Two"}))
+				Expect(d[0].AppGuids).To(Equal([]string{"app-1", "app-2"}))
+				Expect(d[0].Type).To(Equal("logs"))
+				Expect(d[0].DrainURL).To(Equal("syslog://your-app.cf-app.com"))
+
+				Expect(d[1].Name).To(Equal("drain-2"))
+				Expect(d[1].Guid).To(Equal("guid-2"))
+				Expect(d[1].Apps).To(Equal([]string{"My App One"}))
+				Expect(d[1].AppGuids).To(Equal([]string{"app-1"}))
+				Expect(d[1].Type).To(Equal("metrics"))
+				Expect(d[1].DrainURL).To(Equal("https://your-app2.cf-app.com?drain-type=metrics"))
+
+				// 4 => 2 service instance fetches + 2 binding fetches
+				Expect(curler.methods).To(ConsistOf("GET", "GET", "GET", "GET"))
+				Expect(curler.bodies).To(ConsistOf("", "", "", ""))
 			})
 		})
 
-		It("returns the error if requesting the service bindings fails", func() {
-			key = "/v2/user_provided_service_instances/drain-1/service_bindings"
+		It("returns the error if requesting the service credential bindings fails", func() {
+			key = "/v3/service_credential_bindings?include=app&service_instance_guids=guid-1,guid-2&type=app"
 			curler.errs[key] = errors.New("some error")
 
 			_, err := c.Drains("space-guid")
 			Expect(err).To(MatchError("some error"))
 		})
 
-		It("returns the error if unmarshalling the service bindings fails", func() {
-			key = "/v2/user_provided_service_instances/drain-1/service_bindings"
+		It("returns the error if unmarshalling the service credential bindings fails", func() {
+			key = "/v3/service_credential_bindings?include=app&service_instance_guids=guid-1,guid-2&type=app"
 			curler.resps[key] = "no json"
 
 			_, err := c.Drains("space-guid")
@@ -174,7 +157,7 @@ var _ = Describe("ListDrainsClient", func() {
 	})
 
 	It("returns the error if requesting the service instances fails", func() {
-		key = "/v2/user_provided_service_instances?q=space_guid:space-guid"
+		key = "/v3/service_instances?space_guids=space-guid&type=user-provided"
 		curler.errs[key] = errors.New("some error")
 
 		_, err := c.Drains("space-guid")
@@ -182,7 +165,7 @@ var _ = Describe("ListDrainsClient", func() {
 	})
 
 	It("returns the error if unmarshalling the service instances response fails", func() {
-		key = "/v2/user_provided_service_instances?q=space_guid:space-guid"
+		key = "/v3/service_instances?space_guids=space-guid&type=user-provided"
 		curler.resps[key] = "not a JSON"
 
 		_, err := c.Drains("space-guid")
@@ -252,243 +235,207 @@ func (s *stubCurler) Curl(URL, method, body string) ([]byte, error) {
 }
 
 var serviceInstancesJSONpage1 = `{
-   "total_results": 2,
-   "total_pages": 2,
-   "prev_url": null,
-   "next_url": "/v2/user_provided_service_instances?q=space_guid:space-guid&page:2",
+   "pagination": {
+      "total_results": 2,
+      "total_pages": 2,
+      "next": {
+         "href": "https://api.example.com/v3/service_instances?page=2&per_page=1&space_guids=space-guid&type=user-provided"
+      },
+      "previous": null
+   },
    "resources": [
       {
-		 "metadata": {
-			"guid": "guid-1"
-		 },
-         "entity": {
-            "name": "drain-1",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "service_bindings_url": "/v2/user_provided_service_instances/drain-1/service_bindings"
-         }
+         "guid": "guid-1",
+         "name": "drain-1",
+         "type": "user-provided",
+         "syslog_drain_url": "syslog://your-app.cf-app.com"
       }
    ]
 }`
 
 var serviceInstancesJSONpage2 = `{
-   "total_results": 2,
-   "total_pages": 2,
-   "prev_url": "/v2/user_provided_service_instances?q=space_guid:space-guid&page:1",
-   "next_url": null,
-   "resources": [
-      {
-		 "metadata": {
-			"guid": "guid-2"
-		 },
-         "entity": {
-            "name": "drain-2",
-            "syslog_drain_url": "https://your-app2.cf-app.com?drain-type=metrics",
-            "service_bindings_url": "/v2/user_provided_service_instances/drain-2/service_bindings"
-         }
-      }
-   ]
-}`
-
-var serviceInstancesJSON2 = `{
-   "total_results": 1,
-   "total_pages": 1,
-   "prev_url": null,
-   "next_url": null,
-   "resources": [
-      {
-		 "metadata": {
-			"guid": "guid-2"
-		 },
-         "entity": {
-            "name": "drain-2",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "service_bindings_url": "/v2/user_provided_service_instances/drain-2/service_bindings"
-         }
-      }
-   ]
-}`
-
-var serviceBindingsJSON1page1 = `{
-   "total_results": 2,
-   "total_pages": 2,
-   "prev_url": null,
-   "next_url": "/v2/user_provided_service_instances/drain-1/service_bindings&page:2",
-   "resources": [
-      {
-         "entity": {
-            "app_guid": "app-1",
-            "service_instance_guid": "drain-1",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-1"
-         }
-      }
-   ]
-}`
-
-var serviceBindingsJSON1page2 = `{
-   "total_results": 2,
-   "total_pages": 2,
-   "prev_url": "/v2/user_provided_service_instances/drain-1/service_bindings&page:1",
-   "next_url": null,
-   "resources": [
-      {
-         "entity": {
-            "app_guid": "app-2",
-            "service_instance_guid": "drain-1",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-2"
-         }
+   "pagination": {
+      "total_results": 2,
+      "total_pages": 2,
+      "next": null,
+      "previous": {
+         "href": "https://api.example.com/v3/service_instances?page=1&per_page=1&space_guids=space-guid&type=user-provided"
       }
-   ]
-}`
-
-var serviceBindingsJSON2 = `{
-   "total_results": 1,
-   "total_pages": 1,
-   "prev_url": null,
-   "next_url": null,
+   },
    "resources": [
       {
-         "entity": {
-            "app_guid": "app-1",
-            "service_instance_guid": "drain-2",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-1"
-         }
+         "guid": "guid-2",
+         "name": "drain-2",
+         "type": "user-provided",
+         "syslog_drain_url": "https://your-app2.cf-app.com?drain-type=metrics"
       }
    ]
 }`
 
-var serviceBindingsJSON3 = `{
-   "total_results": 6,
-   "total_pages": 1,
-   "prev_url": null,
-   "next_url": null,
+var serviceInstancesJSONBatch = `{
+   "pagination": {
+      "total_results": 4,
+      "total_pages": 1,
+      "next": null,
+      "previous": null
+   },
    "resources": [
       {
-         "entity": {
-            "app_guid": "app-1",
-            "service_instance_guid": "drain-1",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-1"
-         }
+         "guid": "guid-1",
+         "name": "drain-1",
+         "type": "user-provided",
+         "syslog_drain_url": "syslog://your-app.cf-app.com"
       },
       {
-         "entity": {
-            "app_guid": "app-2",
-            "service_instance_guid": "drain-2",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-2"
-         }
+         "guid": "guid-2",
+         "name": "drain-2",
+         "type": "user-provided",
+         "syslog_drain_url": "syslog://your-app.cf-app.com"
       },
       {
-         "entity": {
-            "app_guid": "app-3",
-            "service_instance_guid": "drain-3",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-3"
-         }
+         "guid": "guid-3",
+         "name": "drain-3",
+         "type": "user-provided",
+         "syslog_drain_url": "syslog://your-app.cf-app.com"
       },
       {
-         "entity": {
-            "app_guid": "app-4",
-            "service_instance_guid": "drain-4",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-4"
-         }
-      },
-      {
-         "entity": {
-            "app_guid": "app-4",
-            "service_instance_guid": "drain-5",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-4"
-         }
-      },
-      {
-         "entity": {
-            "app_guid": "app-4",
-            "service_instance_guid": "drain-6",
-            "syslog_drain_url": "syslog://your-app.cf-app.com",
-            "name": null,
-            "app_url": "/v2/apps/app-4"
-         }
+         "guid": "guid-4",
+         "name": "drain-4",
+         "type": "user-provided",
+         "tags": ["space-drain:some-app-guid"],
+         "syslog_drain_url": "syslog-tls://your-app.cf-app.com:6514?drain-type=all"
       }
    ]
 }`
 
-var appJSONcall1 = `{
+var bindingsJSONpage1 = `{
    "pagination": {
       "total_results": 3,
-      "total_pages": 1,
-      "next": null,
+      "total_pages": 2,
+      "next": {
+         "href": "https://api.example.com/v3/service_credential_bindings?include=app&page=2&per_page=2&service_instance_guids=guid-1%2Cguid-2&type=app"
+      },
       "previous": null
    },
    "resources": [
       {
-         "guid": "app-1",
-         "name": "My App One"
-      },
-      {
-         "guid": "app-2",
-         "name": "My App Two"
+         "guid": "binding-1",
+         "type": "app",
+         "relationships": {
+            "app": { "data": { "guid": "app-1" } },
+            "service_instance": { "data": { "guid": "guid-1" } }
+         }
       },
       {
-         "guid": "app-3",
-         "name": "My App Three"
+         "guid": "binding-2",
+         "type": "app",
+         "relationships": {
+            "app": { "data": { "guid": "app-1" } },
+            "service_instance": { "data": { "guid": "guid-2" } }
+         }
       }
-   ]
+   ],
+   "included": {
+      "apps": [
+         { "guid": "app-1", "name": "My App One" }
+      ]
+   }
 }`
 
-var appJSONcall2 = `{
+var bindingsJSONpage2 = `{
    "pagination": {
-      "total_results": 1,
-      "total_pages": 1,
+      "total_results": 3,
+      "total_pages": 2,
       "next": null,
       "previous": null
    },
    "resources": [
       {
-         "guid": "app-4",
-         "name": "My App Four"
+         "guid": "binding-3",
+         "type": "app",
+         "relationships": {
+            "app": { "data": { "guid": "app-2" } },
+            "service_instance": { "data": { "guid": "guid-1" } }
+         }
       }
-   ]
+   ],
+   "included": {
+      "apps": [
+         { "guid": "app-2", "name": "My App Two" }
+      ]
+   }
 }`
 
-var appJSONpage1 = `{
+var bindingsJSONBatch1 = `{
    "pagination": {
-      "total_results": 2,
-      "total_pages": 2,
-      "next": "/v3/apps?guids=app-1,app-2&page=2",
+      "total_results": 3,
+      "total_pages": 1,
+      "next": null,
       "previous": null
    },
    "resources": [
       {
-         "guid": "app-1",
-         "name": "My App One"
+         "guid": "binding-1",
+         "type": "app",
+         "relationships": {
+            "app": { "data": { "guid": "app-1" } },
+            "service_instance": { "data": { "guid": "guid-1" } }
+         }
+      },
+      {
+         "guid": "binding-2",
+         "type": "app",
+         "relationships": {
+            "app": { "data": { "guid": "app-2" } },
+            "service_instance": { "data": { "guid": "guid-1" } }
+         }
+      },
+      {
+         "guid": "binding-3",
+         "type": "app",
+         "relationships": {
+            "app": { "data": { "guid": "app-3" } },
+            "service_instance": { "data": { "guid": "guid-3" } }
+         }
       }
-   ]
+   ],
+   "included": {
+      "apps": [
+         { "guid": "app-1", "name": "My App One" },
+         { "guid": "app-2", "name": "My App Two" },
+         { "guid": "app-3", "name": "My App Three" }
+      ]
+   }
 }`
 
-var appJSONpage2 = `{
+var bindingsJSONBatch2 = `{
    "pagination": {
-      "total_results": 2,
-      "total_pages": 2,
+      "total_results": 1,
+      "total_pages": 1,
       "next": null,
-      "previous": "/v3/apps?guids=app-1,app-2&page=1"
+      "previous": null
    },
    "resources": [
       {
-         "guid": "app-2",
-         "name": "My App Two"
+         "guid": "binding-4",
+         "type": "app",
+         "relationships": {
+            "app": { "data": { "guid": "app-4" } },
+            "service_instance": { "data": { "guid": "guid-4" } }
+         }
       }
-   ]
+   ],
+   "included": {
+      "apps": [
+         { "guid": "app-4", "name": "My App Four" }
+      ]
+   }
 }`
+
+var _ = Describe("Drain", func() {
+	It("knows drains created by a space or org drain app", func() {
+		d := drain.Drain{Tags: []string{"other", drain.SpaceDrainTag("app-guid")}}
+		Expect(d.CreatedBySpaceDrain()).To(BeTrue())
+		Expect(drain.Drain{Tags: []string{"other"}}.CreatedBySpaceDrain()).To(BeFalse())
+	})
+})
@@ -0,0 +1,13 @@
+package drainurl_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDrainurl(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Drainurl Suite")
+}
@@ -0,0 +1,20 @@
+package drainurl
+
+import "net/url"
+
+// Equal reports whether two drain URLs are equal, ignoring the order of
+// their query parameters.
+func Equal(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return a == b
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return a == b
+	}
+
+	ua.RawQuery = ua.Query().Encode()
+	ub.RawQuery = ub.Query().Encode()
+	return ua.String() == ub.String()
+}
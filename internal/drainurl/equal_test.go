@@ -0,0 +1,23 @@
+package drainurl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/drainurl"
+)
+
+var _ = Describe("Equal", func() {
+	DescribeTable("compares drain URLs", func(a, b string, equal bool) {
+		Expect(drainurl.Equal(a, b)).To(Equal(equal))
+	},
+		Entry("same URL", "syslog://logs.example.com:514", "syslog://logs.example.com:514", true),
+		Entry("reordered query", "https://logs.example.com?a=1&b=2", "https://logs.example.com?b=2&a=1", true),
+		Entry("different host", "syslog://a.example.com:514", "syslog://b.example.com:514", false),
+		Entry("different query value", "https://logs.example.com?drain-type=logs", "https://logs.example.com?drain-type=all", false),
+		Entry("missing query", "https://logs.example.com?drain-type=logs", "https://logs.example.com", false),
+		Entry("unparsable URLs", "://bad", "://bad", true),
+		Entry("unparsable and valid URL", "://bad", "syslog://logs.example.com:514", false),
+	)
+})
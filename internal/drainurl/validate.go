@@ -0,0 +1,128 @@
+// Package drainurl validates syslog drain URLs before they are handed to
+// Cloud Controller. Cloud Controller accepts any URL for a user provided
+// service, while Loggregator silently ignores drains it cannot parse.
+package drainurl
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var supportedSchemes = []string{"syslog", "syslog-tls", "https"}
+
+// queryParams are the query parameters Loggregator understands, mapped to
+// their valid values. A nil slice accepts any value.
+var queryParams = map[string][]string{
+	"drain-type":                 {"logs", "metrics", "all"},
+	"disable-metadata":           {"true", "false"},
+	"omit-metadata":              {"true", "false"},
+	"ssl-strict-internal":        {"true", "false"},
+	"include-metrics-deprecated": {"true", "false"},
+}
+
+// KnownQueryParam reports whether the query parameter is one Loggregator
+// understands. Other query parameters are typically credentials, which
+// Validate only accepts with AllowCredentialParams.
+func KnownQueryParam(name string) bool {
+	_, ok := queryParams[name]
+	return ok
+}
+
+// Option changes how Validate checks a drain URL.
+type Option func(*options)
+
+type options struct {
+	credentialParams bool
+}
+
+// AllowCredentialParams accepts query parameters Loggregator does not know
+// with any value. They are taken to be credentials, e.g. the placeholders
+// drain manifests are exported with.
+func AllowCredentialParams() Option {
+	return func(o *options) {
+		o.credentialParams = true
+	}
+}
+
+// Validate checks that the given URL is a drain URL Loggregator can write
+// to. The scheme has to be syslog, syslog-tls or https and the URL needs a
+// host. Syslog drains also need a port, https drains default to 443. Only
+// query parameters known to Loggregator are accepted unless
+// AllowCredentialParams is given.
+func Validate(drainURL string, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	u, err := url.Parse(drainURL)
+	if err != nil {
+		return err
+	}
+
+	if !contains(supportedSchemes, u.Scheme) {
+		return fmt.Errorf(
+			"unsupported scheme %q, expected one of %s",
+			u.Scheme,
+			strings.Join(supportedSchemes, ", "),
+		)
+	}
+
+	if u.Hostname() == "" {
+		return fmt.Errorf("missing host")
+	}
+
+	if u.Port() == "" {
+		if u.Scheme != "https" {
+			return fmt.Errorf("missing port, %s drains require a port", u.Scheme)
+		}
+	} else {
+		port, err := strconv.Atoi(u.Port())
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %q", u.Port())
+		}
+	}
+
+	query := u.Query()
+	var names []string
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		valid, ok := queryParams[name]
+		if !ok {
+			if o.credentialParams {
+				continue
+			}
+			return fmt.Errorf("unknown query parameter %q", name)
+		}
+
+		for _, v := range query[name] {
+			if valid != nil && !contains(valid, v) {
+				return fmt.Errorf(
+					"invalid value %q for query parameter %q, expected one of %s",
+					v,
+					name,
+					strings.Join(valid, ", "),
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+)
+
+// MetricClient creates metrics that are emitted to Loggregator. It is
+// implemented by the pulseemitter.PulseEmitter.
+type MetricClient interface {
+	NewCounterMetric(name string, opts ...pulseemitter.MetricOption) pulseemitter.CounterMetric
+	NewGaugeMetric(name, unit string, opts ...pulseemitter.MetricOption) pulseemitter.GaugeMetric
+}
+
+// Forwarder copies the counters and gauges of a registry to a
+// MetricClient. Every label combination becomes a metric tagged with its
+// labels. Histograms are not forwarded.
+type Forwarder struct {
+	r *Registry
+	c MetricClient
+
+	counters map[string]*forwardedCounter
+	gauges   map[string]pulseemitter.GaugeMetric
+}
+
+type forwardedCounter struct {
+	metric pulseemitter.CounterMetric
+	last   float64
+}
+
+func NewForwarder(r *Registry, c MetricClient) *Forwarder {
+	return &Forwarder{
+		r:        r,
+		c:        c,
+		counters: make(map[string]*forwardedCounter),
+		gauges:   make(map[string]pulseemitter.GaugeMetric),
+	}
+}
+
+// Run forwards the metrics on every interval until the context is done.
+// The metrics are forwarded a last time before it returns.
+func (f *Forwarder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			f.Forward()
+			return
+		case <-ticker.C:
+			f.Forward()
+		}
+	}
+}
+
+// Forward increments the forwarded counters by how much the counters grew
+// since the last forward and sets the forwarded gauges.
+func (f *Forwarder) Forward() {
+	for _, s := range f.r.samples() {
+		key := s.name + "\xff" + s.key
+		switch s.typ {
+		case "counter":
+			fc, ok := f.counters[key]
+			if !ok {
+				fc = &forwardedCounter{
+					metric: f.c.NewCounterMetric(s.name, pulseemitter.WithTags(s.labels)),
+				}
+				f.counters[key] = fc
+			}
+
+			if s.value > fc.last {
+				fc.metric.Increment(uint64(s.value) - uint64(fc.last))
+				fc.last = s.value
+			}
+		case "gauge":
+			g, ok := f.gauges[key]
+			if !ok {
+				g = f.c.NewGaugeMetric(s.name, "", pulseemitter.WithTags(s.labels))
+				f.gauges[key] = g
+			}
+
+			g.Set(s.value)
+		}
+	}
+}
+
+// sample is the value of a counter or gauge for one label combination.
+type sample struct {
+	name   string
+	typ    string
+	key    string
+	labels map[string]string
+	value  float64
+}
+
+func (r *Registry) samples() []sample {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	var samples []sample
+	for _, f := range families {
+		switch m := f.(type) {
+		case *Counter:
+			m.mu.Lock()
+			samples = append(samples, m.desc.samples(m.values)...)
+			m.mu.Unlock()
+		case *Gauge:
+			m.mu.Lock()
+			samples = append(samples, m.desc.samples(m.values)...)
+			m.mu.Unlock()
+		}
+	}
+
+	return samples
+}
+
+func (d desc) samples(values map[string]float64) []sample {
+	var samples []sample
+	for _, key := range sortedKeys(values) {
+		labels := make(map[string]string, len(d.labelNames))
+		if len(d.labelNames) > 0 {
+			for i, v := range strings.Split(key, "\xff") {
+				labels[d.labelNames[i]] = v
+			}
+		}
+
+		samples = append(samples, sample{
+			name:   d.n,
+			typ:    d.typ,
+			key:    key,
+			labels: labels,
+			value:  values[key],
+		})
+	}
+
+	return samples
+}
@@ -0,0 +1,93 @@
+package metrics_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/metrics"
+	"code.cloudfoundry.org/cf-drain-cli/internal/testhelper"
+)
+
+var _ = Describe("Forwarder", func() {
+	var (
+		r      *metrics.Registry
+		client *testhelper.SpyMetricClient
+		f      *metrics.Forwarder
+	)
+
+	BeforeEach(func() {
+		r = metrics.NewRegistry()
+		client = testhelper.NewMetricClient()
+		f = metrics.NewForwarder(r, client)
+	})
+
+	It("forwards how much counters grew since the last forward", func() {
+		c := r.NewCounter("reconciles_total", "Reconciles.")
+		c.Add(3)
+
+		f.Forward()
+		Expect(client.GetMetric("reconciles_total").Delta()).To(Equal(uint64(3)))
+
+		c.Add(2)
+		f.Forward()
+		Expect(client.GetMetric("reconciles_total").Delta()).To(Equal(uint64(5)))
+
+		f.Forward()
+		Expect(client.GetMetric("reconciles_total").Delta()).To(Equal(uint64(5)))
+	})
+
+	It("forwards counters with labels", func() {
+		c := r.NewCounter("binds_total", "Binds.", "result")
+		c.Inc("success")
+
+		f.Forward()
+		Expect(client.GetMetric("binds_total").Delta()).To(Equal(uint64(1)))
+	})
+
+	It("forwards gauges", func() {
+		g := r.NewGauge("apps", "Apps.", "state")
+		g.Set(7, "bound")
+
+		f.Forward()
+		Expect(client.GetMetric("apps").GaugeValue()).To(Equal(7.0))
+
+		g.Set(4, "bound")
+		f.Forward()
+		Expect(client.GetMetric("apps").GaugeValue()).To(Equal(4.0))
+	})
+
+	It("forwards on every interval until the context is done", func() {
+		g := r.NewGauge("apps", "Apps.")
+		g.Set(7)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f.Run(ctx, 10*time.Millisecond)
+		}()
+
+		Eventually(func() float64 {
+			m := client.GetMetric("apps")
+			if m == nil {
+				return 0
+			}
+			return m.GaugeValue()
+		}).Should(Equal(7.0))
+
+		g.Set(4)
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(client.GetMetric("apps").GaugeValue()).To(Equal(4.0))
+	})
+
+	It("does not forward histograms", func() {
+		r.NewHistogram("duration_seconds", "Duration.", nil).Observe(1)
+
+		f.Forward()
+		Expect(client.GetMetric("duration_seconds")).To(BeNil())
+	})
+})
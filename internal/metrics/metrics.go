@@ -0,0 +1,373 @@
+// Package metrics keeps counters, gauges and histograms in memory and
+// writes them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds, in seconds, used when a
+// histogram is created without buckets.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics written by WriteText.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+type family interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.families {
+		if existing.name() == f.name() {
+			panic(fmt.Sprintf("metric %s registered twice", f.name()))
+		}
+	}
+	r.families = append(r.families, f)
+}
+
+// WriteText writes every metric in the Prometheus text format, in the
+// order the metrics were created.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics for Prometheus to scrape.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteText(w)
+}
+
+// desc describes a metric and the names of its labels.
+type desc struct {
+	n          string
+	help       string
+	typ        string
+	labelNames []string
+}
+
+func (d desc) name() string {
+	return d.n
+}
+
+func (d desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.n, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.n, d.typ)
+}
+
+// key joins the label values so they can be used as a map key. It panics
+// if the number of values does not match the label names, which is a
+// programming error.
+func (d desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf(
+			"metric %s expects %d label values, got %d",
+			d.n, len(d.labelNames), len(labelValues),
+		))
+	}
+
+	return strings.Join(labelValues, "\xff")
+}
+
+func (d desc) labels(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labelNames) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf(`%s="%s"`, d.labelNames[i], escapeLabelValue(v)))
+		}
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], escapeLabelValue(extra[i+1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a value that only goes up, e.g. the number of requests.
+type Counter struct {
+	desc
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter creates a counter and registers it with the registry.
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		desc:   desc{n: name, help: help, typ: "counter", labelNames: labelNames},
+		values: make(map[string]float64),
+	}
+	r.register(c)
+
+	return c
+}
+
+// Inc increments the counter with the given label values by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter with the given label values by delta. It
+// panics if delta is negative.
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("counter %s cannot decrease", c.n))
+	}
+
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += delta
+}
+
+// Value returns the value of the counter with the given label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.writeHeader(w)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.n, c.labels(key), formatFloat(c.values[key]))
+	}
+}
+
+// Gauge is a value that goes up and down, e.g. the number of bound apps.
+type Gauge struct {
+	desc
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewGauge creates a gauge and registers it with the registry.
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{
+		desc:   desc{n: name, help: help, typ: "gauge", labelNames: labelNames},
+		values: make(map[string]float64),
+	}
+	r.register(g)
+
+	return g
+}
+
+// Set sets the gauge with the given label values.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] = v
+}
+
+// Add adds delta, which may be negative, to the gauge with the given label
+// values.
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] += delta
+}
+
+// Delete removes the gauge with the given label values, e.g. of a space that
+// was deleted.
+func (g *Gauge) Delete(labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.values, key)
+}
+
+// Value returns the value of the gauge with the given label values.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[key]
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.writeHeader(w)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.n, g.labels(key), formatFloat(g.values[key]))
+	}
+}
+
+// Histogram counts observations, e.g. request durations, in buckets.
+type Histogram struct {
+	desc
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	// counts holds the number of observations per bucket, not
+	// cumulative. The last count is for the +Inf bucket.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds and
+// registers it with the registry. DefaultBuckets are used if no buckets
+// are given.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &Histogram{
+		desc:    desc{n: name, help: help, typ: "histogram", labelNames: labelNames},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+	r.register(h)
+
+	return h
+}
+
+// Observe adds an observation to the histogram with the given label
+// values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets)+1)}
+		h.values[key] = hv
+	}
+
+	i := sort.SearchFloat64s(h.buckets, v)
+	hv.counts[i]++
+	hv.sum += v
+	hv.count++
+}
+
+// Count returns the number of observations of the histogram with the given
+// label values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hv, ok := h.values[key]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeHeader(w)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		hv := h.values[key]
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.n, h.labels(key, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.n, h.labels(key, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.n, h.labels(key), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.n, h.labels(key), hv.count)
+	}
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
@@ -0,0 +1,141 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/metrics"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		r   *metrics.Registry
+		out *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		r = metrics.NewRegistry()
+		out = &bytes.Buffer{}
+	})
+
+	It("writes counters", func() {
+		c := r.NewCounter("requests_total", "Total requests.", "code")
+		c.Inc("200")
+		c.Add(2, "200")
+		c.Inc("500")
+
+		Expect(r.WriteText(out)).To(Succeed())
+		Expect(out.String()).To(Equal(`# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{code="200"} 3
+requests_total{code="500"} 1
+`))
+		Expect(c.Value("200")).To(Equal(3.0))
+	})
+
+	It("writes counters without labels", func() {
+		c := r.NewCounter("runs_total", "Total runs.")
+		c.Inc()
+
+		Expect(r.WriteText(out)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("\nruns_total 1\n"))
+	})
+
+	It("panics if a counter decreases", func() {
+		c := r.NewCounter("runs_total", "Total runs.")
+		Expect(func() { c.Add(-1) }).To(Panic())
+	})
+
+	It("writes gauges", func() {
+		g := r.NewGauge("apps", "Apps by state.", "space", "state")
+		g.Set(5, "space-1", "bound")
+		g.Add(-2, "space-1", "bound")
+		g.Set(1, "space-1", "unbound")
+
+		Expect(r.WriteText(out)).To(Succeed())
+		Expect(out.String()).To(Equal(`# HELP apps Apps by state.
+# TYPE apps gauge
+apps{space="space-1",state="bound"} 3
+apps{space="space-1",state="unbound"} 1
+`))
+		Expect(g.Value("space-1", "bound")).To(Equal(3.0))
+	})
+
+	It("does not write deleted gauges", func() {
+		g := r.NewGauge("apps", "Apps by state.", "space", "state")
+		g.Set(5, "space-1", "bound")
+		g.Set(3, "space-2", "bound")
+		g.Delete("space-2", "bound")
+
+		Expect(r.WriteText(out)).To(Succeed())
+		Expect(out.String()).To(Equal(`# HELP apps Apps by state.
+# TYPE apps gauge
+apps{space="space-1",state="bound"} 5
+`))
+	})
+
+	It("writes histograms with cumulative buckets", func() {
+		h := r.NewHistogram("duration_seconds", "Duration.", []float64{1, 0.1}, "method")
+		h.Observe(0.05, "GET")
+		h.Observe(0.1, "GET")
+		h.Observe(0.5, "GET")
+		h.Observe(2, "GET")
+
+		Expect(r.WriteText(out)).To(Succeed())
+		Expect(out.String()).To(Equal(`# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{method="GET",le="0.1"} 2
+duration_seconds_bucket{method="GET",le="1"} 3
+duration_seconds_bucket{method="GET",le="+Inf"} 4
+duration_seconds_sum{method="GET"} 2.65
+duration_seconds_count{method="GET"} 4
+`))
+		Expect(h.Count("GET")).To(Equal(uint64(4)))
+	})
+
+	It("writes metrics in the order they were created", func() {
+		r.NewGauge("b", "B.").Set(1)
+		r.NewCounter("a", "A.").Inc()
+
+		Expect(r.WriteText(out)).To(Succeed())
+		Expect(out.String()).To(MatchRegexp(`(?s)# HELP b .*# HELP a `))
+	})
+
+	It("escapes label values and help", func() {
+		c := r.NewCounter("errors_total", "Errors\\with \"quotes\".", "reason")
+		c.Inc("say \"hi\"\n")
+
+		Expect(r.WriteText(out)).To(Succeed())
+		Expect(out.String()).To(Equal(`# HELP errors_total Errors\\with "quotes".
+# TYPE errors_total counter
+errors_total{reason="say \"hi\"\n"} 1
+`))
+	})
+
+	It("panics if the label values do not match the label names", func() {
+		c := r.NewCounter("requests_total", "Total requests.", "code")
+		Expect(func() { c.Inc() }).To(Panic())
+		Expect(func() { c.Inc("200", "GET") }).To(Panic())
+	})
+
+	It("panics if a metric is registered twice", func() {
+		r.NewCounter("requests_total", "Total requests.")
+		Expect(func() {
+			r.NewGauge("requests_total", "Total requests.")
+		}).To(Panic())
+	})
+
+	It("serves the metrics over HTTP", func() {
+		r.NewCounter("runs_total", "Total runs.").Inc()
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(rec.Code).To(Equal(200))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("text/plain; version=0.0.4"))
+		Expect(rec.Body.String()).To(ContainSubstring("runs_total 1"))
+	})
+})
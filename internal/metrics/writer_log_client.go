@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	loggregator "code.cloudfoundry.org/go-loggregator"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// WriterLogClient writes metrics as JSON lines in the structured log format
+// of the CF metric registrar. Written to an app's stdout, the metrics are
+// turned into Loggregator envelopes of the app.
+type WriterLogClient struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterLogClient(w io.Writer) *WriterLogClient {
+	return &WriterLogClient{
+		w: w,
+	}
+}
+
+type structuredMetric struct {
+	Type  string            `json:"type"`
+	Name  string            `json:"name"`
+	Delta *uint64           `json:"delta,omitempty"`
+	Value *float64          `json:"value,omitempty"`
+	Unit  string            `json:"unit,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+// EmitCounter writes the counter with its delta.
+func (c *WriterLogClient) EmitCounter(name string, opts ...loggregator.EmitCounterOption) {
+	e := &loggregator_v2.Envelope{
+		Timestamp: time.Now().UnixNano(),
+		Message: &loggregator_v2.Envelope_Counter{
+			Counter: &loggregator_v2.Counter{
+				Name:  name,
+				Delta: 1,
+			},
+		},
+		Tags: make(map[string]string),
+	}
+	for _, o := range opts {
+		o(e)
+	}
+
+	delta := e.GetCounter().GetDelta()
+	c.write(structuredMetric{
+		Type:  "counter",
+		Name:  name,
+		Delta: &delta,
+		Tags:  e.Tags,
+	})
+}
+
+// EmitGauge writes every value of the gauge as its own metric.
+func (c *WriterLogClient) EmitGauge(opts ...loggregator.EmitGaugeOption) {
+	e := &loggregator_v2.Envelope{
+		Timestamp: time.Now().UnixNano(),
+		Message: &loggregator_v2.Envelope_Gauge{
+			Gauge: &loggregator_v2.Gauge{
+				Metrics: make(map[string]*loggregator_v2.GaugeValue),
+			},
+		},
+		Tags: make(map[string]string),
+	}
+	for _, o := range opts {
+		o(e)
+	}
+
+	metrics := e.GetGauge().GetMetrics()
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := metrics[name].GetValue()
+		c.write(structuredMetric{
+			Type:  "gauge",
+			Name:  name,
+			Value: &value,
+			Unit:  metrics[name].GetUnit(),
+			Tags:  e.Tags,
+		})
+	}
+}
+
+func (c *WriterLogClient) write(m structuredMetric) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.Write(append(data, '\n'))
+}
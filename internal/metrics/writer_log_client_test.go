@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"bytes"
+
+	loggregator "code.cloudfoundry.org/go-loggregator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cf-drain-cli/internal/metrics"
+)
+
+var _ = Describe("WriterLogClient", func() {
+	var (
+		out *bytes.Buffer
+		c   *metrics.WriterLogClient
+	)
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		c = metrics.NewWriterLogClient(out)
+	})
+
+	It("writes counters as structured metrics", func() {
+		c.EmitCounter(
+			"binds_total",
+			loggregator.WithDelta(3),
+			loggregator.WithEnvelopeTag("result", "success"),
+		)
+
+		Expect(out.String()).To(MatchJSON(`{
+			"type": "counter",
+			"name": "binds_total",
+			"delta": 3,
+			"tags": {"result": "success"}
+		}`))
+	})
+
+	It("writes every gauge value as a structured metric", func() {
+		c.EmitGauge(
+			loggregator.WithGaugeValue("apps", 7, ""),
+			loggregator.WithGaugeValue("cpu", 1.5, "percent"),
+		)
+
+		lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+		Expect(lines).To(HaveLen(2))
+		Expect(string(lines[0])).To(MatchJSON(`{"type": "gauge", "name": "apps", "value": 7}`))
+		Expect(string(lines[1])).To(MatchJSON(`{"type": "gauge", "name": "cpu", "value": 1.5, "unit": "percent"}`))
+	})
+})
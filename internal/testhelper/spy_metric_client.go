@@ -12,6 +12,7 @@ type TestMetric interface {
 }
 
 type SpyMetricClient struct {
+	mu      sync.Mutex
 	metrics map[string]TestMetric
 }
 
@@ -26,12 +27,29 @@ func (s *SpyMetricClient) NewCounterMetric(
 	opts ...pulseemitter.MetricOption,
 ) pulseemitter.CounterMetric {
 	m := &SpyMetric{}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics[name] = m
+
+	return m
+}
+
+func (s *SpyMetricClient) NewGaugeMetric(
+	name string,
+	unit string,
+	opts ...pulseemitter.MetricOption,
+) pulseemitter.GaugeMetric {
+	m := &SpyGaugeMetric{}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.metrics[name] = m
 
 	return m
 }
 
 func (s *SpyMetricClient) GetMetric(name string) TestMetric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.metrics[name]
 }
 